@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/man"
+	"go.uber.org/atomic"
+)
+
+// assertFailedExitCode is the exit code when any -assert fails.
+const assertFailedExitCode = 3
+
+var (
+	assertFailed atomic.Int64
+	assertReg    = regexp.MustCompile(`^\s*([^=!<>~]+?)\s*(==|!=|>=|<=|!~|=|~|>|<)\s*(.*?)\s*$`)
+
+	// assertions are the -assert expressions parsed at startup.
+	assertions []*Assertion
+)
+
+// Assertion is an -assert expression like status==200, header.Content-Type~json, body.data.count>0,
+// time<500ms or size<1MB.
+type Assertion struct {
+	Expr     string
+	Subject  string
+	Op       string
+	Expected string
+}
+
+func parseAssertion(expr string) (*Assertion, error) {
+	subs := assertReg.FindStringSubmatch(expr)
+	if len(subs) == 0 {
+		return nil, fmt.Errorf("bad assert %q, expected like status==200", expr)
+	}
+
+	op := subs[2]
+	if op == "=" {
+		op = "=="
+	}
+	a := &Assertion{Expr: expr, Subject: subs[1], Op: op, Expected: subs[3]}
+	if err := a.validate(); err != nil {
+		return nil, fmt.Errorf("bad assert %q: %w", expr, err)
+	}
+	return a, nil
+}
+
+// validate checks the subject, the regex, the duration and the size of the assertion before sending the requests.
+func (a *Assertion) validate() error {
+	regex := a.Op == "~" || a.Op == "!~"
+	switch {
+	case a.Subject == "status", a.Subject == "body", strings.HasPrefix(a.Subject, "header."):
+	case strings.HasPrefix(a.Subject, "body."):
+		if p := strings.TrimPrefix(a.Subject, "body."); isJqExpr(p) {
+			if _, err := parseJq(p); err != nil {
+				return err
+			}
+		}
+	case a.Subject == "time", a.Subject == "size":
+		if regex {
+			return fmt.Errorf("%s does not support %s", a.Subject, a.Op)
+		}
+		if a.Subject == "time" {
+			_, err := time.ParseDuration(a.Expected)
+			return err
+		}
+		_, err := man.ParseBytes(a.Expected)
+		return err
+	default:
+		return fmt.Errorf("unknown subject %s, available: status, header.X, body, body.path, time, size", a.Subject)
+	}
+
+	if regex {
+		_, err := regexp.Compile(a.Expected)
+		return err
+	}
+	return nil
+}
+
+// parseAssertions parses the -assert expressions at startup, so that the bad ones are rejected before sending.
+func parseAssertions(exprs []string) []*Assertion {
+	assertions := make([]*Assertion, 0, len(exprs))
+	for _, expr := range exprs {
+		a, err := parseAssertion(expr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions
+}
+
+// checkAssertions checks the parsed assertions against the response, prints the failures, counts and returns them.
+func checkAssertions(assertions []*Assertion, res *http.Response, body []byte, elapsed time.Duration) (failures []string) {
+	for _, a := range assertions {
+		expr := a.Expr
+		actual, ok, err := a.Check(res, body, elapsed)
+		switch {
+		case err != nil:
+			failures = append(failures, "assert "+expr+": "+err.Error())
+			fmt.Println(Color("✗ assert "+expr+": "+err.Error(), Red))
+		case !ok:
+			failures = append(failures, fmt.Sprintf("assert %s failed, expected: %s %s, actual: %s",
+				expr, a.Op, a.Expected, actual))
+			fmt.Println(Color("✗ assert "+expr+" failed", Red)+",",
+				"expected:", Color(a.Op+" "+a.Expected, Green)+",", "actual:", Color(actual, Red))
+		default:
+			if HasPrintOption(printVerbose) {
+				fmt.Println(Color("✓ assert "+expr, Green))
+			}
+			continue
+		}
+		assertFailed.Inc()
+	}
+	return failures
+}
+
+// Check evaluates the assertion, returns the actual value and whether it passes.
+func (a *Assertion) Check(res *http.Response, body []byte, elapsed time.Duration) (string, bool, error) {
+	switch {
+	case a.Subject == "status":
+		return a.compare(strconv.Itoa(res.StatusCode))
+	case strings.HasPrefix(a.Subject, "header."):
+		return a.compare(res.Header.Get(strings.TrimPrefix(a.Subject, "header.")))
+	case a.Subject == "body":
+		return a.compare(string(body))
+	case strings.HasPrefix(a.Subject, "body."):
+		results, err := queryJSON(body, strings.TrimPrefix(a.Subject, "body."))
+		if err != nil {
+			return "", false, err
+		}
+		actual := ""
+		if len(results) > 0 {
+			actual = jsonScalar(results[0])
+		}
+		return a.compare(actual)
+	case a.Subject == "time":
+		expected, err := time.ParseDuration(a.Expected)
+		if err != nil {
+			return "", false, err
+		}
+		return elapsed.String(), compareOrdered(elapsed, expected, a.Op), nil
+	case a.Subject == "size":
+		expected, err := man.ParseBytes(a.Expected)
+		if err != nil {
+			return "", false, err
+		}
+		return man.Bytes(uint64(len(body))), compareOrdered(uint64(len(body)), expected, a.Op), nil
+	}
+
+	return "", false, fmt.Errorf("unknown subject %s, available: status, header.X, body, body.path, time, size", a.Subject)
+}
+
+func (a *Assertion) compare(actual string) (string, bool, error) {
+	switch a.Op {
+	case "~", "!~":
+		re, err := regexp.Compile(a.Expected)
+		if err != nil {
+			return actual, false, err
+		}
+		return actual, re.MatchString(actual) == (a.Op == "~"), nil
+	}
+
+	af, aErr := strconv.ParseFloat(actual, 64)
+	ef, eErr := strconv.ParseFloat(a.Expected, 64)
+	if aErr == nil && eErr == nil {
+		return actual, compareOrdered(af, ef, a.Op), nil
+	}
+	return actual, compareOrdered(actual, a.Expected, a.Op), nil
+}
+
+func compareOrdered[T time.Duration | uint64 | float64 | string](a, b T, op string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// jsonScalar returns the string value for JSON string, and the raw JSON for others.
+func jsonScalar(v []byte) string {
+	v = bytes.TrimSpace(v)
+	var s string
+	if len(v) > 0 && v[0] == '"' && json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
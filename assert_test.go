@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAssertion(t *testing.T) {
+	res := &http.Response{StatusCode: 500, Header: http.Header{"Content-Type": {"application/json"}}}
+	body := []byte(`{"data":{"count":3,"name":"bingoo"}}`)
+
+	cases := map[string]bool{
+		"status==200":               false,
+		"status>=500":               true,
+		"status=500":                true,
+		"header.Content-Type~json":  true,
+		"header.Content-Type!~json": false,
+		"body.data.count>0":         true,
+		"body.data.count>10":        false,
+		"body.data.name==bingoo":    true,
+		"body~bingoo":               true,
+		"time<500ms":                true,
+		"time>1s":                   false,
+		"size<1KB":                  true,
+	}
+	for expr, want := range cases {
+		a, err := parseAssertion(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, ok, err := a.Check(res, body, 100*time.Millisecond)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if ok != want {
+			t.Errorf("%s: got %t, actual %s", expr, ok, actual)
+		}
+	}
+
+	for _, expr := range []string{"status", "code==200", "time<abc", "size~1KB", "body~[a"} {
+		if _, err := parseAssertion(expr); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var (
+	xmlEncodingReg     = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding\s*=\s*["']([\w.:\-]+)["']`)
+	htmlMetaCharsetReg = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w.:\-]+)`)
+)
+
+// detectCharset detects the charset of the body from the Content-Type, the XML prolog or the HTML meta tag.
+func detectCharset(body []byte, contentType string) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+
+	head := body[:min(len(body), 1024)]
+	if subs := xmlEncodingReg.FindSubmatch(head); len(subs) > 0 {
+		return string(subs[1])
+	}
+	if subs := htmlMetaCharsetReg.FindSubmatch(head); len(subs) > 0 {
+		return string(subs[1])
+	}
+
+	return ""
+}
+
+func lookupCharset(charset string) encoding.Encoding {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "utf8") {
+		return nil
+	}
+
+	e, err := htmlindex.Get(charset)
+	if err != nil {
+		log.Printf("unknown charset %s: %v", charset, err)
+		return nil
+	}
+	return e
+}
+
+// toUTF8 transcodes the body in the charset specified by -charset, or detected, to UTF-8.
+func toUTF8(body []byte, contentType string) []byte {
+	charset := respCharset
+	if charset == "" {
+		charset = detectCharset(body, contentType)
+	}
+
+	e := lookupCharset(charset)
+	if e == nil {
+		return body
+	}
+
+	d, err := e.NewDecoder().Bytes(body)
+	if err != nil {
+		log.Printf("decode body from charset %s failed: %v", charset, err)
+		return body
+	}
+	return bytes.TrimPrefix(d, []byte("\xef\xbb\xbf"))
+}
+
+// fromUTF8 encodes s to the charset specified by -req-charset for request bodies.
+func fromUTF8(s string) string {
+	e := lookupCharset(reqCharset)
+	if e == nil {
+		return s
+	}
+
+	d, err := e.NewEncoder().String(s)
+	if err != nil {
+		log.Fatalf("encode to charset %s failed: %v", reqCharset, err)
+	}
+	return d
+}
+
+// withReqCharset appends the charset parameter to the content type when -req-charset is specified.
+func withReqCharset(contentType string) string {
+	if reqCharset == "" {
+		return contentType
+	}
+	return contentType + "; charset=" + reqCharset
+}
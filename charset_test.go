@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestToUTF8(t *testing.T) {
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("你好")
+
+	for _, c := range []struct {
+		body, contentType string
+	}{
+		{gbk, "text/plain; charset=GBK"},
+		{`<?xml version="1.0" encoding="GB18030"?><a>` + gbk + `</a>`, "application/xml"},
+		{`<html><head><meta http-equiv="Content-Type" content="text/html; charset=gbk"></head>` + gbk + `</html>`, "text/html"},
+	} {
+		if got := string(toUTF8([]byte(c.body), c.contentType)); !strings.Contains(got, "你好") {
+			t.Errorf("toUTF8(%q, %s) = %q", c.body, c.contentType, got)
+		}
+	}
+
+	reqCharset = "GBK"
+	defer func() { reqCharset = "" }()
+	if got := fromUTF8("你好"); got != gbk {
+		t.Errorf("fromUTF8 got %q, want %q", got, gbk)
+	}
+
+	req := NewRequest("http://a.b", "POST")
+	if got := req.encodeBody(`{"a":"你好"}`); got != `{"a":"`+gbk+`"}` || req.Req.Header.Get("Content-Type") != "application/json; charset=GBK" {
+		t.Errorf("encodeBody(json) = %q, Content-Type %s", got, req.Req.Header.Get("Content-Type"))
+	}
+	if raw := "\xff\xfe\x00你好"; req.encodeBody(raw) != raw {
+		t.Error("encodeBody should keep the non JSON body")
+	}
+	if got := createParamBody(map[string]string{"k": "你好"}); got != "k="+url.QueryEscape(gbk) {
+		t.Errorf("createParamBody() = %q", got)
+	}
+}
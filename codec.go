@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/bufbuild/protocompile"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Codec converts between JSON and a binary format, like protobuf, msgpack and cbor.
+type Codec struct {
+	// Decode converts the binary body to JSON.
+	Decode func(data []byte) ([]byte, error)
+	// Encode converts the JSON to the binary body.
+	Encode      func(data []byte) ([]byte, error)
+	ContentType string
+}
+
+var codecs = map[string]*Codec{
+	"msgpack": {
+		ContentType: "application/msgpack",
+		Decode: func(data []byte) ([]byte, error) {
+			var v any
+			if err := msgpack.Unmarshal(data, &v); err != nil {
+				return nil, err
+			}
+			return json.Marshal(jsonCompatible(v))
+		},
+		Encode: func(data []byte) ([]byte, error) {
+			v, err := unmarshalJSONNumbers(data)
+			if err != nil {
+				return nil, err
+			}
+			return msgpack.Marshal(v)
+		},
+	},
+	"cbor": {
+		ContentType: "application/cbor",
+		Decode: func(data []byte) ([]byte, error) {
+			var v any
+			if err := cbor.Unmarshal(data, &v); err != nil {
+				return nil, err
+			}
+			return json.Marshal(jsonCompatible(v))
+		},
+		Encode: func(data []byte) ([]byte, error) {
+			v, err := unmarshalJSONNumbers(data)
+			if err != nil {
+				return nil, err
+			}
+			return cbor.Marshal(v)
+		},
+	},
+	"protobuf": {
+		ContentType: "application/x-protobuf",
+		Decode: func(data []byte) ([]byte, error) {
+			m, err := newProtoMessage(protoMsg)
+			if err != nil {
+				return nil, err
+			}
+			if err := proto.Unmarshal(data, m); err != nil {
+				return nil, err
+			}
+			j, err := protojson.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			// protojson adds random spaces to keep its output unstable, compact it.
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, j); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		Encode: func(data []byte) ([]byte, error) {
+			m, err := newProtoMessage(ss.Or(protoReqMsg, protoMsg))
+			if err != nil {
+				return nil, err
+			}
+			if err := protojson.Unmarshal(data, m); err != nil {
+				return nil, err
+			}
+			return proto.Marshal(m)
+		},
+	},
+}
+
+// codecByContentType finds the codec by the response Content-Type.
+func codecByContentType(contentType string) *Codec {
+	switch {
+	case ss.ContainsFold(contentType, "msgpack"):
+		return codecs["msgpack"]
+	case ss.ContainsFold(contentType, "cbor"):
+		return codecs["cbor"]
+	case ss.ContainsFold(contentType, "protobuf"):
+		return codecs["protobuf"]
+	}
+	return nil
+}
+
+// decodeCodecBody converts protobuf, msgpack and cbor bodies to JSON for printing.
+func decodeCodecBody(data []byte, contentType string) []byte {
+	c := codecByContentType(contentType)
+	if c == nil || len(data) == 0 || c == codecs["protobuf"] && protoFile == "" {
+		return data
+	}
+
+	j, err := c.Decode(data)
+	if err != nil {
+		log.Printf("decode %s body failed: %v", contentType, err)
+		return data
+	}
+	return j
+}
+
+// encodeCodecBody converts the JSON request body to the format specified by -req-codec.
+func encodeCodecBody(data []byte) ([]byte, string) {
+	c, ok := codecs[strings.ToLower(reqCodec)]
+	if !ok {
+		log.Fatalf("unknown request codec %s, available: msgpack, cbor, protobuf", reqCodec)
+	}
+
+	d, err := c.Encode(data)
+	if err != nil {
+		log.Fatalf("encode body by %s failed: %v", reqCodec, err)
+	}
+	return d, c.ContentType
+}
+
+func unmarshalJSONNumbers(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return jsonNumbers(v), nil
+}
+
+// jsonNumbers converts json.Number to int64 or float64, so that it can be encoded as numbers.
+func jsonNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = jsonNumbers(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = jsonNumbers(e)
+		}
+	}
+	return v
+}
+
+// jsonCompatible converts maps with non-string keys, which are allowed in msgpack and cbor, to map[string]any.
+func jsonCompatible(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[fmt.Sprintf("%v", k)] = jsonCompatible(e)
+		}
+		return m
+	case map[string]any:
+		for k, e := range t {
+			t[k] = jsonCompatible(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = jsonCompatible(e)
+		}
+	case cbor.Tag:
+		return map[string]any{"tag": t.Number, "content": jsonCompatible(t.Content)}
+	}
+	return v
+}
+
+var protoFiles []protoreflect.FileDescriptor
+
+// loadProtoFiles loads the .proto source file, or the descriptor set file (protoc -o), specified by -proto.
+func loadProtoFiles() ([]protoreflect.FileDescriptor, error) {
+	if protoFiles != nil {
+		return protoFiles, nil
+	}
+	if protoFile == "" {
+		return nil, fmt.Errorf("-proto is required to decode/encode protobuf")
+	}
+
+	if strings.HasSuffix(protoFile, ".proto") {
+		c := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+				ImportPaths: []string{filepath.Dir(protoFile), "."},
+			}),
+		}
+		files, err := c.Compile(context.Background(), filepath.Base(protoFile))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			protoFiles = append(protoFiles, f)
+		}
+		return protoFiles, nil
+	}
+
+	data, err := os.ReadFile(protoFile)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse descriptor set %s: %w", protoFile, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, err
+	}
+	files.RangeFiles(func(f protoreflect.FileDescriptor) bool {
+		protoFiles = append(protoFiles, f)
+		return true
+	})
+	return protoFiles, nil
+}
+
+// newProtoMessage creates a dynamic message by its full name like pkg.Person, or short name like Person.
+func newProtoMessage(name string) (*dynamicpb.Message, error) {
+	if name == "" {
+		return nil, fmt.Errorf("-proto-msg is required to decode/encode protobuf")
+	}
+
+	files, err := loadProtoFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var found protoreflect.MessageDescriptor
+	var find func(messages protoreflect.MessageDescriptors)
+	find = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len() && found == nil; i++ {
+			m := messages.Get(i)
+			if string(m.FullName()) == name || string(m.Name()) == name {
+				found = m
+				return
+			}
+			find(m.Messages())
+		}
+	}
+	for _, f := range files {
+		if find(f.Messages()); found != nil {
+			return dynamicpb.NewMessage(found), nil
+		}
+	}
+
+	return nil, fmt.Errorf("message %s not found in %s", name, protoFile)
+}
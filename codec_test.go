@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	protoFile = filepath.Join(dir, "person.proto")
+	protoMsg = "Person"
+	defer func() { protoFile, protoMsg, protoFiles = "", "", nil }()
+	if err := os.WriteFile(protoFile, []byte(`syntax = "proto3";
+package demo;
+message Person {
+  string name = 1;
+  int32 age = 2;
+}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct{ codec, contentType string }{
+		{"msgpack", "application/msgpack"},
+		{"cbor", "application/cbor"},
+		{"protobuf", "application/x-protobuf"},
+	} {
+		reqCodec = c.codec
+		body, contentType := encodeCodecBody([]byte(`{"name":"bingoo","age":18}`))
+		if contentType != c.contentType {
+			t.Errorf("%s: got content type %s", c.codec, contentType)
+		}
+
+		got := string(decodeCodecBody(body, contentType))
+		if got != `{"age":18,"name":"bingoo"}` && got != `{"name":"bingoo","age":18}` {
+			t.Errorf("%s: got %s", c.codec, got)
+		}
+	}
+	reqCodec = ""
+}
@@ -9,7 +9,7 @@ import (
 
 const (
 	Gray = uint8(iota + 90)
-	_    // Red
+	Red
 	Green
 	Yellow
 	_ // Blue
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetscapeCookie is a line of the Netscape/curl cookie file:
+// domain, include subdomains, path, secure, expires (unix seconds, 0 for session cookie), name and value.
+type NetscapeCookie struct {
+	Domain            string
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	HttpOnly          bool
+	Expires           int64
+	Name              string
+	Value             string
+}
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+func parseNetscapeCookies(data string) (cookies []*NetscapeCookie, err error) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 { // empty value may be trimmed
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("bad cookie line %d: %q", n, line)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad expires of cookie line %d: %w", n, err)
+		}
+		cookies = append(cookies, &NetscapeCookie{
+			Domain:            fields[0],
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly:          httpOnly,
+			Expires:           expires,
+			Name:              fields[5],
+			Value:             fields[6],
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+func (c *NetscapeCookie) expired(now time.Time) bool {
+	return c.Expires > 0 && c.Expires <= now.Unix()
+}
+
+func (c *NetscapeCookie) String() string {
+	bools := map[bool]string{true: "TRUE", false: "FALSE"}
+	prefix := ""
+	if c.HttpOnly {
+		prefix = httpOnlyPrefix
+	}
+	return prefix + strings.Join([]string{
+		c.Domain, bools[c.IncludeSubdomains], c.Path, bools[c.Secure],
+		strconv.FormatInt(c.Expires, 10), c.Name, c.Value,
+	}, "\t")
+}
+
+// URL returns the URL of the cookie to set into the cookie jar.
+func (c *NetscapeCookie) URL() *url.URL {
+	scheme := "http"
+	if c.Secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: strings.TrimPrefix(c.Domain, "."), Path: c.Path}
+}
+
+// HTTPCookie returns the cookie to set into the cookie jar, empty Domain for the host-only cookie.
+func (c *NetscapeCookie) HTTPCookie() *http.Cookie {
+	hc := &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path, Secure: c.Secure, HttpOnly: c.HttpOnly}
+	if c.IncludeSubdomains {
+		hc.Domain = c.Domain
+	}
+	if c.Expires > 0 {
+		hc.Expires = time.Unix(c.Expires, 0)
+	}
+	return hc
+}
+
+// CookieFile is the cookies loaded by -cookie, and the ones set by the servers to be saved by -cookie-jar.
+type CookieFile struct {
+	Cookies []*NetscapeCookie
+	mu      sync.Mutex
+}
+
+var cookieFile *CookieFile
+
+// loadCookieFile loads the Netscape cookie file of -cookie.
+func loadCookieFile(filename string) *CookieFile {
+	f := &CookieFile{}
+	if filename == "" {
+		return f
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Fatalf("read cookie file %s failed: %v", filename, err)
+	}
+	cookies, err := parseNetscapeCookies(string(data))
+	if err != nil {
+		log.Fatalf("parse cookie file %s failed: %v", filename, err)
+	}
+	now := time.Now()
+	for _, c := range cookies {
+		if !c.expired(now) {
+			f.Cookies = append(f.Cookies, c)
+		}
+	}
+	return f
+}
+
+func (f *CookieFile) restoreCookies(jar http.CookieJar) {
+	for _, c := range f.Cookies {
+		jar.SetCookies(c.URL(), []*http.Cookie{c.HTTPCookie()})
+	}
+}
+
+// SetCookies updates the cookies set by the server of the URL, and saves them to -cookie-jar file.
+func (f *CookieFile) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range cookies {
+		nc := &NetscapeCookie{
+			Domain: u.Hostname(), Path: c.Path, Secure: c.Secure, HttpOnly: c.HttpOnly,
+			Name: c.Name, Value: c.Value,
+		}
+		if c.Domain != "" {
+			nc.Domain, nc.IncludeSubdomains = "."+strings.TrimPrefix(c.Domain, "."), true
+		}
+		if nc.Path == "" || nc.Path[0] != '/' {
+			nc.Path = defaultCookiePath(u.Path)
+		}
+		if c.MaxAge > 0 {
+			nc.Expires = now.Unix() + int64(c.MaxAge)
+		} else if !c.Expires.IsZero() {
+			nc.Expires = c.Expires.Unix()
+		}
+
+		kept := f.Cookies[:0]
+		for _, old := range f.Cookies {
+			if !(old.Domain == nc.Domain && old.Path == nc.Path && old.Name == nc.Name) {
+				kept = append(kept, old)
+			}
+		}
+		f.Cookies = kept
+		if c.MaxAge >= 0 && !nc.expired(now) {
+			f.Cookies = append(f.Cookies, nc)
+		}
+	}
+
+	if cookieJarFile != "" {
+		if err := f.Save(cookieJarFile); err != nil {
+			log.Printf("save cookie jar %s failed: %v", cookieJarFile, err)
+		}
+	}
+}
+
+// Save writes the cookies in the Netscape format which can be read by curl -b and wget --load-cookies.
+func (f *CookieFile) Save(filename string) error {
+	lines := make([]string, 0, len(f.Cookies))
+	for _, c := range f.Cookies {
+		lines = append(lines, c.String())
+	}
+	sort.Strings(lines)
+	data := "# Netscape HTTP Cookie File\n# This file was generated by gurl! Edit at your own risk.\n\n" +
+		strings.Join(lines, "\n") + "\n"
+	return os.WriteFile(filename, []byte(data), 0o600)
+}
+
+// defaultCookiePath returns the default path of the cookie by RFC 6265 section 5.1.4.
+func defaultCookiePath(p string) string {
+	if p == "" || p[0] != '/' {
+		return "/"
+	}
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return "/"
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseNetscapeCookies(t *testing.T) {
+	data := "# Netscape HTTP Cookie File\n" +
+		".a.b\tTRUE\t/\tTRUE\t0\tsid\t123\n" +
+		"#HttpOnly_a.b\tFALSE\t/api\tFALSE\t1\ttk\t\n" +
+		"\n"
+	cookies, err := parseNetscapeCookies(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("len(cookies) = %d, want 2", len(cookies))
+	}
+
+	c := cookies[0]
+	if !c.IncludeSubdomains || !c.Secure || c.Name != "sid" || c.HTTPCookie().Domain != ".a.b" || c.URL().String() != "https://a.b/" {
+		t.Errorf("cookies[0] = %+v", c)
+	}
+	c = cookies[1]
+	if !c.HttpOnly || c.Value != "" || c.HTTPCookie().Domain != "" || c.String() != "#HttpOnly_a.b\tFALSE\t/api\tFALSE\t1\ttk\t" {
+		t.Errorf("cookies[1] = %q", c)
+	}
+
+	if _, err := parseNetscapeCookies("a.b\tTRUE\t/\n"); err == nil {
+		t.Error("expected error for bad line")
+	}
+}
+
+func TestCookieFileSetCookies(t *testing.T) {
+	f := &CookieFile{}
+	u, _ := url.Parse("http://www.a.b/api/login")
+	f.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "1", Domain: "a.b", Path: "/"}, {Name: "tmp", Value: "x"}})
+	if len(f.Cookies) != 2 || f.Cookies[0].Domain != ".a.b" || f.Cookies[1].Domain != "www.a.b" || f.Cookies[1].Path != "/api" {
+		t.Fatalf("cookies = %v", f.Cookies)
+	}
+
+	f.SetCookies(u, []*http.Cookie{{Name: "sid", Domain: ".a.b", Path: "/", MaxAge: -1}})
+	if len(f.Cookies) != 1 || f.Cookies[0].Name != "tmp" {
+		t.Errorf("cookies = %v", f.Cookies)
+	}
+
+	for p, want := range map[string]string{"": "/", "/": "/", "/a": "/", "/a/b": "/a", "/a/b/": "/a/b"} {
+		if got := defaultCookiePath(p); got != want {
+			t.Errorf("defaultCookiePath(%q) = %s, want %s", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/fla9"
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/kballard/go-shellquote"
+)
+
+// CurlCommand is the parsed curl command line, like the one copied by browser devtools "Copy as cURL".
+type CurlCommand struct {
+	Method   string
+	URL      string
+	Headers  []string
+	Data     []string
+	Forms    []string
+	User     string
+	Proxy    string
+	CACert   string
+	Output   string
+	Resolves []string
+	Insecure bool
+	Get      bool
+	Head     bool
+	Compress bool
+}
+
+// curlIgnoredFlags are the curl flags without arguments which are ignored.
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-v": true, "--verbose": true,
+	"-i": true, "--include": true, "-L": true, "--location": true, "-f": true, "--fail": true,
+	"-N": true, "--no-buffer": true, "--http1.1": true, "--http2": true, "-g": true, "--globoff": true,
+}
+
+// parseCurl parses the curl command line.
+func parseCurl(cmd string) (*CurlCommand, error) {
+	// devtools copies multiline commands with trailing backslashes.
+	cmd = strings.NewReplacer("\\\r\n", " ", "\\\n", " ").Replace(strings.TrimSpace(cmd))
+	args, err := shellquote.Split(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("split curl command: %w", err)
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	c := &CurlCommand{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() string {
+			if i+1 >= len(args) {
+				log.Fatalf("curl option %s requires an argument", arg)
+			}
+			i++
+			return args[i]
+		}
+
+		// -XPOST, -HAccept:json and --request=POST styles
+		if k, v, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(k, "--") {
+			arg, args = k, append(args[:i+1], append([]string{v}, args[i+1:]...)...)
+		} else if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && strings.ContainsRune("XHdFuxbAeo", rune(arg[1])) {
+			arg, args = arg[:2], append(args[:i+1], append([]string{arg[2:]}, args[i+1:]...)...)
+		}
+
+		switch arg {
+		case "-X", "--request":
+			c.Method = strings.ToUpper(value())
+		case "-H", "--header":
+			c.Headers = append(c.Headers, value())
+		case "-A", "--user-agent":
+			c.Headers = append(c.Headers, "User-Agent: "+value())
+		case "-e", "--referer":
+			c.Headers = append(c.Headers, "Referer: "+value())
+		case "-b", "--cookie":
+			c.Headers = append(c.Headers, "Cookie: "+value())
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			c.Data = append(c.Data, value())
+		case "--data-urlencode":
+			v := value()
+			if k, val, ok := strings.Cut(v, "="); ok {
+				v = k + "=" + url.QueryEscape(val)
+			} else {
+				v = url.QueryEscape(v)
+			}
+			c.Data = append(c.Data, v)
+		case "--json":
+			c.Data = append(c.Data, value())
+			c.Headers = append(c.Headers, "Content-Type: application/json", "Accept: application/json")
+		case "-F", "--form", "--form-string":
+			c.Forms = append(c.Forms, value())
+		case "-u", "--user":
+			c.User = value()
+		case "-x", "--proxy":
+			c.Proxy = value()
+		case "--cacert":
+			c.CACert = value()
+		case "--resolve":
+			c.Resolves = append(c.Resolves, value())
+		case "-o", "--output":
+			c.Output = value()
+		case "--url":
+			c.URL = value()
+		case "-k", "--insecure":
+			c.Insecure = true
+		case "-G", "--get":
+			c.Get = true
+		case "-I", "--head":
+			c.Head = true
+		case "--compressed":
+			c.Compress = true
+		default:
+			switch {
+			case curlIgnoredFlags[arg]:
+			case strings.HasPrefix(arg, "-"):
+				log.Printf("curl option %s is ignored", arg)
+			default:
+				c.URL = arg
+			}
+		}
+	}
+
+	if c.URL == "" {
+		return nil, fmt.Errorf("no URL found in curl command")
+	}
+	return c, nil
+}
+
+// GurlArgs converts the curl command to the equivalent gurl command line arguments.
+func (c *CurlCommand) GurlArgs() []string {
+	var args []string
+	if c.User != "" {
+		args = append(args, "-auth", c.User)
+	}
+	if c.Proxy != "" {
+		args = append(args, "-proxy", c.Proxy)
+	}
+	if c.Output != "" {
+		args = append(args, "-o", c.Output)
+	}
+	for _, r := range c.Resolves {
+		args = append(args, "-resolve", r)
+	}
+
+	// the multipart parts, f=@a.png to the file item f@a.png with the field name kept, k=v to the form item.
+	var items []string
+	for _, f := range c.Forms {
+		if k, v, ok := strings.Cut(f, "=@"); ok {
+			items = append(items, k+"@"+strings.SplitN(v, ";", 2)[0])
+		} else {
+			items = append(items, f)
+		}
+	}
+	if len(c.Forms) > 0 {
+		args = append(args, "-f")
+	}
+
+	u := c.URL
+	if data := strings.Join(c.Data, "&"); data != "" {
+		if c.Get {
+			u += ss.If(strings.Contains(u, "?"), "&", "?") + data
+		} else {
+			args = append(args, "-b", data)
+		}
+	}
+
+	m := c.Method
+	switch {
+	case m != "":
+	case c.Head:
+		m = "HEAD"
+	case len(c.Data) > 0 && !c.Get, len(c.Forms) > 0:
+		m = "POST"
+	default:
+		m = "GET"
+	}
+	args = append(args, m, u)
+
+	contentType := false
+	for _, h := range c.Headers {
+		if k, v, ok := strings.Cut(h, ":"); ok {
+			items = append(items, strings.TrimSpace(k)+":"+strings.TrimSpace(v))
+			contentType = contentType || strings.EqualFold(strings.TrimSpace(k), "Content-Type")
+		}
+	}
+	// curl sends -d data as the form unless the Content-Type is specified.
+	if len(c.Data) > 0 && !c.Get && len(c.Forms) == 0 && !contentType {
+		items = append(items, "Content-Type:application/x-www-form-urlencoded")
+	}
+	return append(args, items...)
+}
+
+// GurlEnvs returns the NAME=value environment variables for the TLS options. curl verifies the server certificate
+// unless -k, while gurl does not unless TLS_VERIFY=true, and the --cacert is only used when verifying.
+func (c *CurlCommand) GurlEnvs() []string {
+	var envs []string
+	if strings.HasPrefix(c.URL, "https://") || c.CACert != "" {
+		envs = append(envs, "TLS_VERIFY="+strconv.FormatBool(!c.Insecure))
+	}
+	if c.CACert != "" {
+		envs = append(envs, "CERT="+c.CACert)
+	}
+	return envs
+}
+
+// applyFromCurl parses the -from-curl command, and replaces the command line with the equivalent gurl arguments.
+func applyFromCurl() []string {
+	c, err := parseCurl(fromCurl)
+	if err != nil {
+		log.Fatalf("parse -from-curl failed: %v", err)
+	}
+
+	var envs []string
+	for _, e := range c.GurlEnvs() {
+		k, v, _ := strings.Cut(e, "=")
+		_ = os.Setenv(k, v)
+		envs = append(envs, k+"="+shellquote.Join(v))
+	}
+	if c.CACert != "" {
+		caFile = c.CACert
+	}
+	if c.Compress && acceptEncoding == "" {
+		acceptEncoding = "gzip, deflate, br, zstd"
+	}
+
+	args := c.GurlArgs()
+	if toGurl {
+		fmt.Println(strings.Join(append(envs, "gurl", shellquote.Join(args...)), " "))
+		os.Exit(0)
+	}
+	return reparseArgs(args)
+}
+
+// reparseArgs parses the flags in the args, and returns the non-flag ones among them,
+// because fla9 accumulates the non-flag args of all the parses.
+func reparseArgs(args []string) []string {
+	n := len(fla9.Args())
+	if err := fla9.CommandLine.Parse(args); err != nil {
+		log.Fatalf("failed to parse args, %v", err)
+	}
+	return fla9.Args()[n:]
+}
+
+// resolveOverride returns the address specified by -resolve host:port:addr for the address host:port.
+func resolveOverride(addr string) (string, bool) {
+	for _, r := range resolves {
+		parts := strings.SplitN(r, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if net.JoinHostPort(parts[0], parts[1]) == addr {
+			return net.JoinHostPort(strings.Trim(parts[2], "[]"), parts[1]), true
+		}
+	}
+	return "", false
+}
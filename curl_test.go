@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	c, err := parseCurl(`curl 'https://a.b/c?x=1' \
+  -H 'Accept: application/json' -H "Content-Type: application/json" \
+  --data-raw '{"name":"bingoo"}' -u user:pass --compressed -k`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-auth", "user:pass", "-b", `{"name":"bingoo"}`, "POST", "https://a.b/c?x=1",
+		"Accept:application/json", "Content-Type:application/json"}
+	if got := c.GurlArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GurlArgs() = %q, want %q", got, want)
+	}
+	if !c.Insecure || !c.Compress {
+		t.Errorf("Insecure/Compress not parsed: %+v", c)
+	}
+	if got := c.GurlEnvs(); !reflect.DeepEqual(got, []string{"TLS_VERIFY=false"}) {
+		t.Errorf("GurlEnvs() = %q", got)
+	}
+
+	c, err = parseCurl(`curl --cacert ca.pem -d 'x=1&y=2' https://a.b/c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"-b", "x=1&y=2", "POST", "https://a.b/c", "Content-Type:application/x-www-form-urlencoded"}
+	if got := c.GurlArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GurlArgs() = %q, want %q", got, want)
+	}
+	if got := c.GurlEnvs(); !reflect.DeepEqual(got, []string{"TLS_VERIFY=true", "CERT=ca.pem"}) {
+		t.Errorf("GurlEnvs() = %q", got)
+	}
+
+	c, err = parseCurl(`curl -G -XGET --data-urlencode 'q=a b' -d n=1 -F f=@a.png -F k=v -A ua --resolve a.b:443:127.0.0.1 a.b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"-resolve", "a.b:443:127.0.0.1", "-f", "GET", "a.b?q=a+b&n=1", "f@a.png", "k=v", "User-Agent:ua"}
+	if got := c.GurlArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GurlArgs() = %q, want %q", got, want)
+	}
+
+	if _, err := parseCurl(`curl -H 'A: b'`); err == nil {
+		t.Error("expected error for missing URL")
+	}
+}
+
+func TestCurlFormParts(t *testing.T) {
+	var fields, files []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		for k := range r.MultipartForm.Value {
+			fields = append(fields, k+"="+r.FormValue(k))
+		}
+		for k, fhs := range r.MultipartForm.File {
+			files = append(files, k+"@"+filepath.Base(fhs[0].Filename))
+		}
+	}))
+	defer srv.Close()
+
+	fn := filepath.Join(t.TempDir(), "a.png")
+	_ = os.WriteFile(fn, []byte("png"), 0o644)
+	c, err := parseCurl(`curl -F f=@` + fn + `;type=image/png -F k=v ` + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := c.GurlArgs()
+	if want := []string{"-f", "POST", srv.URL, "f@" + fn, "k=v"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("GurlArgs() = %q, want %q", args, want)
+	}
+
+	defer func(m string, f bool) { method, form = m, f }(method, form)
+	method, form = "POST", true
+	req, addrGen := prepareRequest(srv.URL, args[3:], nil)
+	if err := doRequest(req, addrGen); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields, []string{"k=v"}) || !reflect.DeepEqual(files, []string{"f@a.png"}) {
+		t.Errorf("fields %q, files %q", fields, files)
+	}
+}
+
+func TestResolveOverride(t *testing.T) {
+	resolves = []string{"a.b:443:127.0.0.1", "c.d:80:[::1]"}
+	defer func() { resolves = nil }()
+
+	if addr, ok := resolveOverride("a.b:443"); !ok || addr != "127.0.0.1:443" {
+		t.Errorf("resolveOverride(a.b:443) = %s, %v", addr, ok)
+	}
+	if addr, ok := resolveOverride("c.d:80"); !ok || addr != "[::1]:80" {
+		t.Errorf("resolveOverride(c.d:80) = %s, %v", addr, ok)
+	}
+	if _, ok := resolveOverride("a.b:80"); ok {
+		t.Error("resolveOverride(a.b:80) should not be overridden")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ContentDecoder creates a reader to decode the body encoded by a Content-Encoding.
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var contentDecoders = map[string]ContentDecoder{
+	"gzip":   gzipDecoder,
+	"x-gzip": gzipDecoder,
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		// deflate should be zlib wrapped (RFC 9110), but some servers send the raw deflate stream.
+		br := bufio.NewReader(r)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	},
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+func gzipDecoder(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+// RegisterContentDecoder registers a decoder for the Content-Encoding.
+func RegisterContentDecoder(encoding string, d ContentDecoder) {
+	contentDecoders[strings.ToLower(encoding)] = d
+}
+
+// decodeContent decodes r by the Content-Encoding, which may be stacked like `gzip, br`,
+// so the encodings are decoded in the reverse order of their application. The unsupported
+// encoding is kept as it is with a warning. Closing the reader closes the decoders and r.
+func decodeContent(r io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	d := &contentReader{Reader: r}
+	if c, ok := r.(io.Closer); ok {
+		d.closers = append(d.closers, c)
+	}
+
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+
+		decoder, ok := contentDecoders[encoding]
+		if !ok {
+			log.Printf("unsupported Content-Encoding %q, the body is not decoded", encoding)
+			break
+		}
+
+		dr, err := decoder(d.Reader)
+		if err != nil {
+			_ = d.Close()
+			return nil, fmt.Errorf("decode Content-Encoding %q: %w", encoding, err)
+		}
+		d.Reader = dr
+		d.closers = append(d.closers, dr)
+	}
+
+	return d, nil
+}
+
+// contentReader reads the decoded body, and closes the decoders in the reverse order of their creation.
+type contentReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *contentReader) Close() (err error) {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if e := d.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// countReader counts the bytes read from the underlying reader.
+type countReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (n int, err error) {
+	n, err = c.Reader.Read(p)
+	c.n += int64(n)
+	return
+}
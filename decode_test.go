@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecodeContent(t *testing.T) {
+	plain := []byte(`{"hello":"world"}`)
+	encode := func(w io.WriteCloser, buf *bytes.Buffer, data []byte) []byte {
+		_, _ = w.Write(data)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	gz := func(data []byte) []byte { var b bytes.Buffer; return encode(gzip.NewWriter(&b), &b, data) }
+	br := func(data []byte) []byte { var b bytes.Buffer; return encode(brotli.NewWriter(&b), &b, data) }
+	zl := func(data []byte) []byte { var b bytes.Buffer; return encode(zlib.NewWriter(&b), &b, data) }
+	fl := func(data []byte) []byte {
+		var b bytes.Buffer
+		w, _ := flate.NewWriter(&b, flate.DefaultCompression)
+		return encode(w, &b, data)
+	}
+	zs := func(data []byte) []byte {
+		var b bytes.Buffer
+		w, _ := zstd.NewWriter(&b)
+		return encode(w, &b, data)
+	}
+
+	for encoding, data := range map[string][]byte{
+		"":         plain,
+		"gzip":     gz(plain),
+		"deflate":  zl(plain),
+		"Deflate":  fl(plain),
+		"br":       br(plain),
+		"zstd":     zs(plain),
+		"gzip, br": br(gz(plain)),
+	} {
+		r, err := decodeContent(bytes.NewReader(data), encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if got, _ := io.ReadAll(r); !bytes.Equal(got, plain) {
+			t.Errorf("%s: got %q", encoding, got)
+		}
+	}
+
+	r, err := decodeContent(bytes.NewReader(gz(plain)), "compress, gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(r); !bytes.Equal(got, plain) {
+		t.Errorf("unsupported encoding: got %q", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bingoohuang/gg/pkg/man"
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// diffIgnoreHeaders are the volatile headers ignored in -diff by default.
+var diffIgnoreHeaders = []string{"Date", "Age", "Expires", "Last-Modified", "Set-Cookie", "X-Request-Id", "Gurl-N"}
+
+type diffResponse struct {
+	URL    string
+	Err    error
+	Header http.Header
+	Status string
+	Body   []byte
+}
+
+// runDiff fetches all the URLs, concurrently by -c, and diffs the responses against the first one.
+// It exits with code 1 when any difference is found.
+func runDiff(urlAddrs []string, nonFlagArgs []string, reader io.Reader) {
+	responses := fetchDiffResponses(urlAddrs, nonFlagArgs, reader)
+
+	differs := false
+	base := responses[0]
+	for _, r := range responses[1:] {
+		lines := diffResponses(base, r)
+		fmt.Println(Color("--- "+base.URL, Red))
+		fmt.Println(Color("+++ "+r.URL, Green))
+		if len(lines) == 0 {
+			fmt.Println(Color("= identical", Gray))
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		fmt.Println()
+		differs = differs || len(lines) > 0
+	}
+
+	if differs {
+		wireTrace.Close()
+		os.Exit(1)
+	}
+}
+
+// fetchDiffResponses fetches the responses of all the URLs, the connection errors are kept in the responses
+// to be reported as the differences, instead of exiting.
+func fetchDiffResponses(urlAddrs []string, nonFlagArgs []string, reader io.Reader) []diffResponse {
+	defer func(fatal bool) { executeFailedFatal = fatal }(executeFailedFatal)
+	executeFailedFatal = false
+
+	reqs := make([]*Request, len(urlAddrs))
+	for i, urlAddr := range urlAddrs {
+		req, addrGen := prepareRequest(urlAddr, nonFlagArgs, reader)
+		if req.bodyCh != nil {
+			if err := req.NextBody(); err != nil {
+				log.Fatalf("read body: %v", err)
+			}
+		} else {
+			setBody(req)
+		}
+		req.url = addrGen().String()
+		req.EvalItems()
+		reqs[i] = req
+	}
+
+	responses := make([]diffResponse, len(reqs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(benchC, 1))
+	for i, req := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req *Request) {
+			defer func() { <-sem; wg.Done() }()
+			responses[i] = fetchDiffResponse(req)
+		}(i, req)
+	}
+	wg.Wait()
+	return responses
+}
+
+func fetchDiffResponse(req *Request) diffResponse {
+	r := diffResponse{URL: req.url}
+	res, err := req.Response()
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Status, r.Header = res.Status, res.Header
+	r.Body, r.Err = req.Bytes()
+	return r
+}
+
+// diffResponses diffs the status, headers and bodies of the two responses.
+func diffResponses(a, b diffResponse) (lines []string) {
+	if a.Err != nil || b.Err != nil {
+		if fmt.Sprint(a.Err) != fmt.Sprint(b.Err) {
+			lines = append(lines, diffChanged("error", fmt.Sprint(a.Err), fmt.Sprint(b.Err)))
+		}
+		return lines
+	}
+
+	if a.Status != b.Status {
+		lines = append(lines, diffChanged("status", a.Status, b.Status))
+	}
+
+	lines = append(lines, diffHeaders(a.Header, b.Header)...)
+
+	var av, bv any
+	if jsonDecodeNumber(a.Body, &av) == nil && jsonDecodeNumber(b.Body, &bv) == nil {
+		return append(lines, diffJSON("", av, bv)...)
+	}
+	if !bytes.Equal(a.Body, b.Body) {
+		lines = append(lines, diffChanged("body", man.Bytes(uint64(len(a.Body))), man.Bytes(uint64(len(b.Body)))))
+	}
+	return lines
+}
+
+func diffHeaders(a, b http.Header) (lines []string) {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	for _, k := range sortedKeys(keys) {
+		p := "header." + k
+		if diffIgnored(p) || ss.AnyOfFold(k, diffIgnoreHeaders...) {
+			continue
+		}
+		av, aok := a[k]
+		bv, bok := b[k]
+		switch {
+		case !bok:
+			lines = append(lines, diffRemoved(p, strings.Join(av, " ")))
+		case !aok:
+			lines = append(lines, diffAdded(p, strings.Join(bv, " ")))
+		case strings.Join(av, " ") != strings.Join(bv, " "):
+			lines = append(lines, diffChanged(p, strings.Join(av, " "), strings.Join(bv, " ")))
+		}
+	}
+	return lines
+}
+
+// diffJSON diffs the JSON values structurally, the paths are in gjson syntax like a.b.0.c.
+func diffJSON(p string, a, b any) (lines []string) {
+	if diffIgnored(p) {
+		return nil
+	}
+
+	switch at := a.(type) {
+	case map[string]any:
+		bt, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for k := range at {
+			keys[k] = true
+		}
+		for k := range bt {
+			keys[k] = true
+		}
+		for _, k := range sortedKeys(keys) {
+			kp := diffPath(p, k)
+			av, aok := at[k]
+			bv, bok := bt[k]
+			switch {
+			case diffIgnored(kp):
+			case !bok:
+				lines = append(lines, diffRemoved(kp, diffValue(av)))
+			case !aok:
+				lines = append(lines, diffAdded(kp, diffValue(bv)))
+			default:
+				lines = append(lines, diffJSON(kp, av, bv)...)
+			}
+		}
+		return lines
+	case []any:
+		bt, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(at), len(bt)); i++ {
+			ip := diffPath(p, fmt.Sprintf("%d", i))
+			switch {
+			case diffIgnored(ip):
+			case i >= len(bt):
+				lines = append(lines, diffRemoved(ip, diffValue(at[i])))
+			case i >= len(at):
+				lines = append(lines, diffAdded(ip, diffValue(bt[i])))
+			default:
+				lines = append(lines, diffJSON(ip, at[i], bt[i])...)
+			}
+		}
+		return lines
+	}
+
+	if av, bv := diffValue(a), diffValue(b); av != bv {
+		lines = append(lines, diffChanged(ss.Or(p, "."), av, bv))
+	}
+	return lines
+}
+
+func diffPath(p, k string) string {
+	if p == "" {
+		return k
+	}
+	return p + "." + k
+}
+
+// diffIgnored tells whether the path matches any of the -diff-ignore patterns, like data.*.updatedAt.
+func diffIgnored(p string) bool {
+	for _, patterns := range diffIgnores {
+		for _, pattern := range strings.Split(patterns, ",") {
+			if ok, _ := path.Match(strings.TrimSpace(pattern), p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func diffValue(v any) string {
+	d, _ := json.Marshal(v)
+	return string(d)
+}
+
+func diffChanged(p, a, b string) string {
+	return Color("~ "+p+": ", Yellow) + Color(a, Red) + Color(" => ", Gray) + Color(b, Green)
+}
+
+func diffAdded(p, v string) string   { return Color("+ "+p+": "+v, Green) }
+func diffRemoved(p, v string) string { return Color("- "+p+": "+v, Red) }
+
+func jsonDecodeNumber(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if d.More() {
+		return fmt.Errorf("extra data after JSON")
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffResponses(t *testing.T) {
+	diffIgnores = []string{"ts,items.*.at"}
+	defer func() { diffIgnores = nil }()
+
+	a := diffResponse{
+		Status: "200 OK",
+		Header: http.Header{"Server": {"a"}, "Date": {"1"}},
+		Body:   []byte(`{"ts":1,"v":1,"old":true,"items":[{"id":1,"at":1},{"id":2}]}`),
+	}
+	b := diffResponse{
+		Status: "500 Internal Server Error",
+		Header: http.Header{"Server": {"b"}, "Date": {"2"}},
+		Body:   []byte(`{"ts":2,"v":2,"new":"x","items":[{"id":1,"at":2},{"id":3},{"id":4}]}`),
+	}
+
+	want := []string{
+		"~ status: 200 OK => 500 Internal Server Error",
+		"~ header.Server: a => b",
+		"~ items.1.id: 2 => 3",
+		`+ items.2: {"id":4}`,
+		`+ new: "x"`,
+		"- old: true",
+		"~ v: 1 => 2",
+	}
+	if got := diffResponses(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+	if got := diffResponses(a, a); len(got) != 0 {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFetchDiffResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"v":1}`))
+	}))
+	defer srv.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := "http://" + l.Addr().String()
+	_ = l.Close()
+
+	responses := fetchDiffResponses([]string{srv.URL, down}, nil, nil)
+	if responses[0].Err != nil || responses[1].Err == nil || !executeFailedFatal {
+		t.Fatalf("errors %v, %v, executeFailedFatal %t", responses[0].Err, responses[1].Err, executeFailedFatal)
+	}
+	if lines := diffResponses(responses[0], responses[1]); len(lines) != 1 || !strings.HasPrefix(lines[0], "~ error: <nil> => ") {
+		t.Errorf("got %q", lines)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
@@ -92,22 +91,28 @@ func parseContentRange(contentRangeHead string) (*contentRange, error) {
 	}, nil
 }
 
-func downloadFile(req *Request, res *http.Response, filename string) {
-	if ext := filepath.Ext(filename); ext == "" {
+// downloadFile saves the response body to the file, resume means continuing the download by the Content-Range,
+// otherwise the file is truncated and saved as it is named.
+func downloadFile(req *Request, res *http.Response, filename string, resume bool) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if ext := filepath.Ext(filename); resume && ext == "" {
 		contentType := res.Header.Get("Content-Type")
 		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
 			filename += exts[0]
 		}
 	}
 
-	fd, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0o666)
+	if resume {
+		flag = os.O_WRONLY | os.O_CREATE
+	}
+	fd, err := os.OpenFile(filename, flag, 0o666)
 	if err != nil {
 		log.Fatalf("create download file %q failed: %v", filename, err)
 	}
 
 	var cr *contentRange
 	contentRangeHead := res.Header.Get("Content-Range")
-	if contentRangeHead != "" {
+	if resume && contentRangeHead != "" {
 		cr, err = parseContentRange(contentRangeHead)
 		if err != nil {
 			log.Fatalf("parse Content-Range header failed: %v", err)
@@ -123,7 +128,7 @@ func downloadFile(req *Request, res *http.Response, filename string) {
 	printRequestResponseForNonWindows(req, res, true)
 
 	total, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
-	if total == 0 && !chunked(res.TransferEncoding) {
+	if resume && total == 0 && !chunked(res.TransferEncoding) {
 		return
 	}
 
@@ -134,8 +139,8 @@ func downloadFile(req *Request, res *http.Response, filename string) {
 	pb := NewProgressBar(total).Start()
 	br := newProgressBarReader(res.Body, pb)
 
-	if res.Header.Get("Content-Encoding") == "gzip" {
-		reader, err := gzip.NewReader(br)
+	if ce := res.Header.Get("Content-Encoding"); ce != "" {
+		reader, err := decodeContent(br, ce)
 		if err != nil {
 			log.Fatalf("create download file %q failed: %v", filename, err)
 		}
@@ -155,7 +160,7 @@ func downloadFile(req *Request, res *http.Response, filename string) {
 		}
 	}
 
-	if _, err := io.Copy(fd, br); err != nil {
+	if _, err := io.Copy(fd, req.keepBody(br)); err != nil {
 		// A successful Copy returns err == nil, not err == EOF.
 		log.Fatalf("download file %q failed: %v", filename, err)
 	}
@@ -165,3 +170,24 @@ func downloadFile(req *Request, res *http.Response, filename string) {
 	iox.Close(fd, br)
 	fmt.Println()
 }
+
+// keepBodyMax is the max size of the downloaded body kept in memory for the response checks.
+const keepBodyMax = 16 * 1024 * 1024
+
+// keepBody keeps the leading keepBodyMax bytes of the body read, for the response checks like -assert.
+func (b *Request) keepBody(r io.Reader) io.Reader {
+	if !keepResponseBody() {
+		return r
+	}
+	b.rspBody = []byte{}
+	return io.TeeReader(r, bodyKeeper{b})
+}
+
+type bodyKeeper struct{ b *Request }
+
+func (k bodyKeeper) Write(p []byte) (int, error) {
+	if n := keepBodyMax - len(k.b.rspBody); n > 0 {
+		k.b.rspBody = append(k.b.rspBody, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
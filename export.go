@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// ExportedRequest is the built request to be exported as the client code by -export.
+type ExportedRequest struct {
+	Method     string
+	URL        string
+	Headers    [][2]string
+	Body       string
+	Files      [][2]string // multipart file fields, [field, filename]
+	FormFields [][2]string // multipart text fields
+	Compressed bool
+}
+
+var exporters = map[string]func(e *ExportedRequest) string{
+	"curl":   exportCurl,
+	"go":     exportGo,
+	"python": exportPython,
+	"js":     exportJS,
+}
+
+// exportRequest prints the client code of the request instead of sending it.
+func exportRequest(req *Request, addrGen func() *url.URL) {
+	exporter, ok := exporters[strings.ToLower(exportFormat)]
+	if !ok {
+		log.Fatalf("unsupported -export %s, available: curl, go, python, js", exportFormat)
+	}
+	fmt.Println(exporter(buildExportedRequest(req, addrGen)))
+}
+
+func buildExportedRequest(req *Request, addrGen func() *url.URL) *ExportedRequest {
+	req.EvalItems()
+	e := &ExportedRequest{Method: req.Req.Method, URL: req.fullURL(addrGen().String())}
+
+	switch {
+	case len(uploadFiles) > 0:
+		for i, f := range uploadFiles {
+			e.Files = append(e.Files, [2]string{ss.If(len(uploadFiles) == 1, "file", fmt.Sprintf("file-%d", i+1)), f})
+		}
+	case len(req.files) > 0:
+		e.Files, e.FormFields = sortedPairs(req.files), sortedPairs(req.params)
+	default:
+		if req.bodyCh != nil {
+			if err := req.NextBody(); err != nil && err != io.EOF {
+				log.Fatalf("read body failed: %v", err)
+			}
+		} else {
+			setBody(req)
+		}
+		if req.Req.Body != nil {
+			data, err := io.ReadAll(req.Req.Body)
+			if err != nil {
+				log.Fatalf("read body failed: %v", err)
+			}
+			if json.Valid(data) {
+				data = bytes.TrimSpace(data)
+			}
+			e.Body = string(data)
+		}
+	}
+
+	if req.Req.Host != "" {
+		e.Headers = append(e.Headers, [2]string{"Host", req.Req.Host})
+	}
+	if req.Setting.UserAgent != "" && req.Req.Header.Get("User-Agent") == "" {
+		req.Header("User-Agent", req.Setting.UserAgent)
+	}
+	keys := make([]string, 0, len(req.Req.Header))
+	for k := range req.Req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := req.Req.Header.Get(k)
+		switch {
+		case k == "Gurl-Date" || k == "Gurl-N":
+		case k == "Content-Type" && len(e.Files) > 0: // the client sets the multipart boundary itself
+		case k == "Accept-Encoding" && v == acceptEncoding:
+			e.Compressed = true
+		default:
+			e.Headers = append(e.Headers, [2]string{k, v})
+		}
+	}
+	return e
+}
+
+func sortedPairs(m map[string]string) (pairs [][2]string) {
+	for k, v := range m {
+		pairs = append(pairs, [2]string{k, v})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return pairs
+}
+
+// shQuote quotes s in single quotes for the shell.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsQuote quotes s as a JSON string, which is also a valid Python / JavaScript string literal.
+func jsQuote(s string) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func exportCurl(e *ExportedRequest) string {
+	lines := []string{"curl -X " + e.Method + " " + shQuote(e.URL)}
+	for _, h := range e.Headers {
+		lines = append(lines, "-H "+shQuote(h[0]+": "+h[1]))
+	}
+	for _, f := range e.FormFields {
+		lines = append(lines, "--form-string "+shQuote(f[0]+"="+f[1]))
+	}
+	for _, f := range e.Files {
+		lines = append(lines, "-F "+shQuote(f[0]+"=@"+f[1]))
+	}
+	if e.Body != "" {
+		lines = append(lines, "--data-raw "+shQuote(e.Body))
+	}
+	if e.Compressed {
+		lines = append(lines, "--compressed")
+	}
+	return strings.Join(lines, " \\\n  ")
+}
+
+func exportGo(e *ExportedRequest) string {
+	var b strings.Builder
+	imports := []string{"fmt", "io", "log", "net/http"}
+	if len(e.Files) > 0 {
+		imports = append(imports, "bytes", "mime/multipart", "os", "path/filepath")
+	} else if e.Body != "" {
+		imports = append(imports, "strings")
+	}
+	sort.Strings(imports)
+
+	b.WriteString("package main\n\nimport (\n")
+	for _, imp := range imports {
+		b.WriteString("\t" + strconv.Quote(imp) + "\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+
+	bodyArg := "nil"
+	switch {
+	case len(e.Files) > 0:
+		bodyArg = "body"
+		b.WriteString("\tbody := &bytes.Buffer{}\n\tw := multipart.NewWriter(body)\n")
+		for _, f := range e.FormFields {
+			fmt.Fprintf(&b, "\tif err := w.WriteField(%s, %s); err != nil {\n\t\tlog.Fatal(err)\n\t}\n",
+				strconv.Quote(f[0]), strconv.Quote(f[1]))
+		}
+		for _, f := range e.Files {
+			fmt.Fprintf(&b, "\tif data, err := os.ReadFile(%s); err != nil {\n\t\tlog.Fatal(err)\n"+
+				"\t} else if fw, err := w.CreateFormFile(%s, filepath.Base(%s)); err != nil {\n\t\tlog.Fatal(err)\n"+
+				"\t} else if _, err := fw.Write(data); err != nil {\n\t\tlog.Fatal(err)\n\t}\n",
+				strconv.Quote(f[1]), strconv.Quote(f[0]), strconv.Quote(f[1]))
+		}
+		b.WriteString("\tif err := w.Close(); err != nil {\n\t\tlog.Fatal(err)\n\t}\n")
+	case e.Body != "":
+		bodyArg = "body"
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", goQuote(e.Body))
+	}
+
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, %s)\n\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n",
+		strconv.Quote(e.Method), strconv.Quote(e.URL), bodyArg)
+	for _, h := range e.Headers {
+		if h[0] == "Host" {
+			fmt.Fprintf(&b, "\treq.Host = %s\n", strconv.Quote(h[1]))
+		} else {
+			fmt.Fprintf(&b, "\treq.Header.Set(%s, %s)\n", strconv.Quote(h[0]), strconv.Quote(h[1]))
+		}
+	}
+	if len(e.Files) > 0 {
+		b.WriteString("\treq.Header.Set(\"Content-Type\", w.FormDataContentType())\n")
+	}
+
+	b.WriteString("\n\tresp, err := http.DefaultClient.Do(req)\n\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n" +
+		"\tdefer resp.Body.Close()\n\n" +
+		"\tdata, err := io.ReadAll(resp.Body)\n\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n" +
+		"\tfmt.Println(resp.Status)\n\tfmt.Println(string(data))\n}")
+	return b.String()
+}
+
+// goQuote quotes s as a raw string literal if possible, which is more readable for JSON.
+func goQuote(s string) string {
+	if strings.Contains(s, "`") || !strconv.CanBackquote(strings.ReplaceAll(s, "\n", "")) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+func exportPython(e *ExportedRequest) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(&b, "url = %s\n", jsQuote(e.URL))
+	writePyDict(&b, "headers", e.Headers)
+
+	args := "headers=headers"
+	switch {
+	case len(e.Files) > 0:
+		if len(e.FormFields) > 0 {
+			writePyDict(&b, "data", e.FormFields)
+			args += ", data=data"
+		}
+		b.WriteString("files = {\n")
+		for _, f := range e.Files {
+			fmt.Fprintf(&b, "    %s: (%s, open(%s, \"rb\")),\n", jsQuote(f[0]), jsQuote(filepath.Base(f[1])), jsQuote(f[1]))
+		}
+		b.WriteString("}\n")
+		args += ", files=files"
+	case e.Body != "":
+		fmt.Fprintf(&b, "data = %s\n", jsQuote(e.Body))
+		args += ", data=data.encode()"
+	}
+
+	fmt.Fprintf(&b, "\nresp = requests.request(%s, url, %s)\nprint(resp.status_code)\nprint(resp.text)", jsQuote(e.Method), args)
+	return b.String()
+}
+
+func writePyDict(b *strings.Builder, name string, pairs [][2]string) {
+	b.WriteString(name + " = {\n")
+	for _, p := range pairs {
+		fmt.Fprintf(b, "    %s: %s,\n", jsQuote(p[0]), jsQuote(p[1]))
+	}
+	b.WriteString("}\n")
+}
+
+func exportJS(e *ExportedRequest) string {
+	var b strings.Builder
+	body := ""
+	switch {
+	case len(e.Files) > 0:
+		b.WriteString("import { openAsBlob } from \"node:fs\";\n\nconst form = new FormData();\n")
+		for _, f := range e.FormFields {
+			fmt.Fprintf(&b, "form.append(%s, %s);\n", jsQuote(f[0]), jsQuote(f[1]))
+		}
+		for _, f := range e.Files {
+			fmt.Fprintf(&b, "form.append(%s, await openAsBlob(%s), %s);\n", jsQuote(f[0]), jsQuote(f[1]), jsQuote(filepath.Base(f[1])))
+		}
+		b.WriteString("\n")
+		body = "  body: form,\n"
+	case e.Body != "":
+		body = "  body: " + jsQuote(e.Body) + ",\n"
+	}
+
+	fmt.Fprintf(&b, "const resp = await fetch(%s, {\n  method: %s,\n  headers: {\n", jsQuote(e.URL), jsQuote(e.Method))
+	for _, h := range e.Headers {
+		fmt.Fprintf(&b, "    %s: %s,\n", jsQuote(h[0]), jsQuote(h[1]))
+	}
+	b.WriteString("  },\n" + body + "});\nconsole.log(resp.status);\nconsole.log(await resp.text());")
+	return b.String()
+}
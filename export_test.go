@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	e := &ExportedRequest{
+		Method:     "POST",
+		URL:        "http://a.b/c?q=1",
+		Headers:    [][2]string{{"Content-Type", "application/json"}},
+		Body:       `{"name":"it's"}`,
+		Compressed: true,
+	}
+
+	want := `curl -X POST 'http://a.b/c?q=1' \
+  -H 'Content-Type: application/json' \
+  --data-raw '{"name":"it'\''s"}' \
+  --compressed`
+	if got := exportCurl(e); got != want {
+		t.Errorf("exportCurl() = %s, want %s", got, want)
+	}
+
+	want = `const resp = await fetch("http://a.b/c?q=1", {
+  method: "POST",
+  headers: {
+    "Content-Type": "application/json",
+  },
+  body: "{\"name\":\"it's\"}",
+});
+console.log(resp.status);
+console.log(await resp.text());`
+	if got := exportJS(e); got != want {
+		t.Errorf("exportJS() = %s, want %s", got, want)
+	}
+
+	if got := goQuote("a`b"); got != "\"a`b\"" {
+		t.Errorf("goQuote() = %s", got)
+	}
+	if got := goQuote("{\n}"); got != "`{\n}`" {
+		t.Errorf("goQuote() = %s", got)
+	}
+}
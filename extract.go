@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/samber/lo"
+)
+
+// Extraction is an -extract expression like token=data.token, reqID=header:X-Request-Id or
+// ver=regex:version (\d+).
+type Extraction struct {
+	Name string
+	Expr string
+}
+
+var (
+	varRefReg = regexp.MustCompile(`@\{?((?:env\.)?\w+)`)
+
+	// dotEnvNames are the names defined in the .env file, which is loaded into the environment at startup.
+	dotEnvNames = func() map[string]string {
+		m, _ := godotenv.Read()
+		return m
+	}()
+)
+
+func parseExtraction(s string) (*Extraction, error) {
+	name, expr, ok := strings.Cut(s, "=")
+	if name, expr = strings.TrimSpace(name), strings.TrimSpace(expr); !ok || name == "" || expr == "" {
+		return nil, fmt.Errorf("bad extract %q, expected like token=data.token", s)
+	}
+	return &Extraction{Name: name, Expr: expr}, nil
+}
+
+// Extract extracts the value from the response by header:X, regex:pattern, or the JSON path (jq expression).
+func (e *Extraction) Extract(res *http.Response, body []byte) (string, error) {
+	switch {
+	case strings.HasPrefix(e.Expr, "header:"):
+		k := strings.TrimPrefix(e.Expr, "header:")
+		if v := res.Header.Values(k); len(v) > 0 {
+			return v[0], nil
+		}
+		return "", fmt.Errorf("header %s not found", k)
+	case strings.HasPrefix(e.Expr, "regex:"):
+		re, err := regexp.Compile(strings.TrimPrefix(e.Expr, "regex:"))
+		if err != nil {
+			return "", err
+		}
+		subs := re.FindSubmatch(body)
+		if len(subs) == 0 {
+			return "", fmt.Errorf("regex %s not matched", re)
+		}
+		return string(subs[len(subs)-1]), nil
+	}
+
+	results, err := queryJSON(body, e.Expr)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 || string(results[0]) == "null" {
+		return "", fmt.Errorf("path %s not found", e.Expr)
+	}
+	return jsonScalar(results[0]), nil
+}
+
+// extractValues captures values from the response into the valuer, and the .env file by -extract-save,
+// so that the later requests can refer them like Authorization:Bearer @token.
+func extractValues(res *http.Response, body []byte) {
+	saved := map[string]string{}
+	for _, s := range extracts {
+		e, err := parseExtraction(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		v, err := e.Extract(res, body)
+		if err != nil {
+			log.Printf("extract %s failed: %v", e.Name, err)
+			continue
+		}
+
+		valuer.Map[e.Name] = v
+		saved[e.Name] = v
+		if HasPrintOption(printVerbose) {
+			log.Printf("extracted %s: %s", e.Name, v)
+		}
+	}
+
+	if extractSave && len(saved) > 0 {
+		if err := saveEnvFile(".env", saved); err != nil {
+			log.Printf("save .env failed: %v", err)
+		}
+	}
+}
+
+// isExtractName tells whether the name is declared by -extract.
+func isExtractName(name string) bool {
+	for _, s := range extracts {
+		if n, _, _ := strings.Cut(s, "="); strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// refersCapturedVar tells whether s refers @name which is declared by -extract, captured, defined in .env,
+// or the environment variable like @env.HOME.
+func refersCapturedVar(s string) bool {
+	for _, subs := range varRefReg.FindAllStringSubmatch(s, -1) {
+		_, captured := valuer.Map[subs[1]]
+		if _, ok := lookupEnvVar(subs[1]); ok || captured || isExtractName(subs[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupEnvVar looks up the variable defined in .env, or the environment variable referred explicitly like @env.HOME,
+// other environment variables like @HOME are not resolved, to avoid sending the secrets in the environment by accident.
+func lookupEnvVar(name string) (string, bool) {
+	if k, ok := strings.CutPrefix(name, "env."); ok {
+		return os.LookupEnv(k)
+	}
+	if _, ok := dotEnvNames[name]; ok {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// saveEnvFile updates or appends the NAME=value lines in the env file, keeping other lines as they are.
+func saveEnvFile(filename string, values map[string]string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	names := lo.Keys(values)
+	sort.Strings(names)
+	for _, name := range names {
+		line := name + "=" + envQuote(values[name])
+		found := false
+		for i, l := range lines {
+			if k, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(l), "export "), "="); ok &&
+				strings.TrimSpace(k) == name {
+				lines[i], found = line, true
+			}
+		}
+		if !found {
+			lines = append(lines, line)
+		}
+	}
+
+	return os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+func envQuote(v string) string {
+	if strings.ContainsAny(v, " \t\r\n#\"'\\$") {
+		return strconv.Quote(v)
+	}
+	return v
+}
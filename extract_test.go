@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtraction(t *testing.T) {
+	res := &http.Response{Header: http.Header{"X-Request-Id": {"r1"}}}
+	body := []byte(`{"data":{"token":"abc","n":3},"msg":"version 12"}`)
+
+	cases := map[string]string{
+		"token=data.token":        "abc",
+		"n=.data.n":               "3",
+		"rid=header:X-Request-Id": "r1",
+		`ver=regex:version (\d+)`: "12",
+	}
+	for s, want := range cases {
+		e, err := parseExtraction(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := e.Extract(res, body); err != nil || got != want {
+			t.Errorf("%s: got %s, %v", s, got, err)
+		}
+	}
+
+	e, _ := parseExtraction("x=data.missing")
+	if _, err := e.Extract(res, body); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSaveEnvFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), ".env")
+	_ = os.WriteFile(fn, []byte("# demo\nexport token=old\nA=1\n"), 0o644)
+
+	if err := saveEnvFile(fn, map[string]string{"token": "new", "msg": "a b"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(fn)
+	if want := "# demo\ntoken=new\nA=1\nmsg=\"a b\"\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestLookupEnvVar(t *testing.T) {
+	t.Setenv("GURL_SECRET", "s")
+	t.Setenv("GURL_DOTENV", "d")
+	dotEnvNames["GURL_DOTENV"] = "d"
+	t.Cleanup(func() { delete(dotEnvNames, "GURL_DOTENV") })
+
+	if _, ok := lookupEnvVar("GURL_SECRET"); ok {
+		t.Error("GURL_SECRET should not be resolved without env. prefix")
+	}
+	if v, ok := lookupEnvVar("env.GURL_SECRET"); !ok || v != "s" {
+		t.Errorf("env.GURL_SECRET: got %q, %t", v, ok)
+	}
+	if v, ok := lookupEnvVar("GURL_DOTENV"); !ok || v != "d" {
+		t.Errorf("GURL_DOTENV: got %q, %t", v, ok)
+	}
+	if !refersCapturedVar("Bearer @env.GURL_SECRET") || refersCapturedVar("Bearer @GURL_SECRET") {
+		t.Error("refersCapturedVar mismatched")
+	}
+}
+
+func TestCapturedVars(t *testing.T) {
+	valuer.Map["user_1"], valuer.Map["tok"] = "u1", "a"
+	t.Cleanup(func() { delete(valuer.Map, "user_1"); delete(valuer.Map, "tok"); valuer.ClearCache() })
+
+	id, _ := Eval("@uuid_1")
+	if again, _ := Eval("@uuid_1"); again != id {
+		t.Errorf("@uuid_1 should be cached, got %s and %s", id, again)
+	}
+	valuer.ClearCache()
+	if again, _ := Eval("@uuid_1"); again == id {
+		t.Error("@uuid_1 should be generated again after ClearCache")
+	}
+	if v, _ := Eval("@user_1"); v != "u1" {
+		t.Errorf("@user_1 = %q, should be kept after ClearCache", v)
+	}
+
+	req, _ := prepareRequest("http://a.b/p", []string{"t==@tok", "x==1"}, nil)
+	req.EvalItems()
+	if got := req.fullURL(req.url); got != "http://a.b/p?x=1&t=a" {
+		t.Errorf("fullURL() = %s", got)
+	}
+	valuer.Map["tok"] = "b"
+	req.EvalItems()
+	if got := req.fullURL(req.url); got != "http://a.b/p?x=1&t=b" {
+		t.Errorf("fullURL() = %s after the token changed", got)
+	}
+}
@@ -31,6 +31,11 @@ func filter(args []string) []string {
 			continue
 		}
 
+		if isLineProtocol(arg) {
+			filteredArgs = append(filteredArgs, arg)
+			continue
+		}
+
 		if inSlice(strings.ToUpper(arg), methodList) {
 			method = strings.ToUpper(arg)
 			methodSpecifiedInArgs = true
@@ -71,6 +76,11 @@ func filter(args []string) []string {
 			method = "POST"
 		} else {
 			for _, v := range args {
+				if isLineProtocol(v) {
+					method = "POST"
+					break
+				}
+
 				subs := keyReg.FindStringSubmatch(v)
 				if len(subs) == 0 {
 					continue
@@ -33,6 +33,56 @@ var (
 
 	createDemoEnv bool
 	unixSocket    string
+	jsonQuery     string
+	tableFormat   string
+	traceFile     string
+
+	acceptEncoding string
+	respCharset    string
+	reqCharset     string
+
+	protoFile   string
+	protoMsg    string
+	protoReqMsg string
+	reqCodec    string
+
+	outputFile string
+
+	diffMode    bool
+	diffIgnores []string
+	asserts     []string
+	extracts    []string
+	extractSave bool
+	junitFile   string
+
+	fromCurl string
+	toGurl   bool
+	resolves []string
+
+	exportFormat string
+
+	postmanEnv  string
+	postmanList bool
+
+	openapiFile string
+	operationID string
+
+	sessionName    string
+	cookieFileName string
+	cookieJarFile  string
+
+	followRedirects bool
+	maxRedirs       int
+	keepMethods     []string
+	redirectAuth    string
+
+	retryTimes   int
+	retryStatus  string
+	retryWait    time.Duration
+	retryMaxWait time.Duration
+	retryForce   bool
+
+	replMode bool
 )
 
 func init() {
@@ -59,6 +109,44 @@ func init() {
 	fla9.IntVar(&benchC, "c", 1, "")
 	flagEnvVar(&body, "body,b", "", "", "BODY")
 	flagEnvVar(&dns, "dns", "", "", "DNS")
+	fla9.StringVar(&jsonQuery, "q", "", "")
+	fla9.StringVar(&tableFormat, "table", "", "")
+	fla9.StringVar(&traceFile, "trace-file", "", "")
+	fla9.StringVar(&acceptEncoding, "accept-encoding", "gzip, deflate, br, zstd", "")
+	fla9.StringVar(&respCharset, "charset", "", "")
+	fla9.StringVar(&reqCharset, "req-charset", "", "")
+	fla9.StringVar(&protoFile, "proto", "", "")
+	fla9.StringVar(&protoMsg, "proto-msg", "", "")
+	fla9.StringVar(&protoReqMsg, "proto-req-msg", "", "")
+	fla9.StringVar(&reqCodec, "req-codec", "", "")
+	fla9.StringVar(&outputFile, "o", "", "")
+	fla9.BoolVar(&diffMode, "diff", false, "")
+	fla9.StringsVar(&diffIgnores, "diff-ignore", nil, "")
+	fla9.StringsVar(&asserts, "assert", nil, "")
+	fla9.StringsVar(&extracts, "extract", nil, "")
+	fla9.BoolVar(&extractSave, "extract-save", false, "")
+	fla9.StringVar(&junitFile, "junit", "", "")
+	fla9.StringVar(&fromCurl, "from-curl", "", "")
+	fla9.BoolVar(&toGurl, "to-gurl", false, "")
+	fla9.StringsVar(&resolves, "resolve", nil, "")
+	fla9.StringVar(&exportFormat, "export", "", "")
+	fla9.StringVar(&postmanEnv, "postman-env", "", "")
+	fla9.BoolVar(&postmanList, "list", false, "")
+	fla9.StringVar(&openapiFile, "openapi", "", "")
+	fla9.StringVar(&operationID, "op", "", "")
+	fla9.StringVar(&sessionName, "session", "", "")
+	fla9.StringVar(&cookieFileName, "cookie", "", "")
+	fla9.StringVar(&cookieJarFile, "cookie-jar", "", "")
+	fla9.BoolVar(&followRedirects, "follow", true, "")
+	fla9.IntVar(&maxRedirs, "max-redirs", 10, "")
+	fla9.StringsVar(&keepMethods, "keep-method", nil, "")
+	fla9.StringVar(&redirectAuth, "redirect-auth", "domain", "")
+	fla9.IntVar(&retryTimes, "retry", 0, "")
+	fla9.StringVar(&retryStatus, "retry-status", "429,503", "")
+	fla9.DurationVar(&retryWait, "retry-wait", time.Second, "")
+	fla9.DurationVar(&retryMaxWait, "retry-max-wait", 30*time.Second, "")
+	fla9.BoolVar(&retryForce, "retry-force", false, "")
+	fla9.BoolVar(&replMode, "i", false, "")
 }
 
 const (
@@ -79,6 +167,8 @@ const (
 	quietFileUploadDownloadProgressing
 	freeInnerJSONTag
 	optionDisableProxy
+	printTable
+	printHex
 )
 
 func parsePrintOption(s string) {
@@ -101,6 +191,8 @@ func parsePrintOption(s string) {
 	AdjustPrintOption(&s, 'r', printRaw)
 	AdjustPrintOption(&s, 'C', printCountingItems)
 	AdjustPrintOption(&s, 'N', optionDisableProxy)
+	AdjustPrintOption(&s, 'T', printTable)
+	AdjustPrintOption(&s, 'X', printHex)
 
 	if s != "" {
 		log.Fatalf("unknown print option: %s", s)
@@ -126,6 +218,13 @@ func HasPrintOption(flags uint32) bool {
 const help = `gurl is a Go implemented cURL-like cli tool for humans.
 Usage:
 	gurl [flags] [METHOD] URL [URL] [ITEM [ITEM]]
+	gurl [flags] run api.http [NAME...]    Run the requests (or the named ones) in .http file of VS Code REST Client format
+	gurl [flags] run flow.yaml [NAME...]   Run the workflow steps with extract, assert, repeat, foreach and skip_if
+	gurl [flags] run api.postman_collection.json [FOLDER/NAME...]   Run the requests (or the selected folders/requests) in Postman v2.1 collection
+	gurl [flags] -i BASE_URL [HEADER...]   Interactive shell, like GET /users id==3, POST /users name=x, header X-Token:abc, with Tab completion of the paths
+	gurl history [KEYWORD...]              List the latest requests in ~/.local/share/gurl/history.jsonl (matching all the keywords), GURL_HISTORY=off to disable
+	gurl history ID                        Show the recorded args, headers, body (secrets redacted) and response summary of the history
+	gurl [flags] replay ID|last [URL] [METHOD] [ITEM...]   Replay the history, the URL, method and items with the same keys override the recorded ones
 flags:
   -unix-socket,s    Using unix socket file
   -u                HTTP request URL
@@ -134,7 +233,51 @@ flags:
   -version -v       Print Version Number
   -f                Submitting the data as a form
   -gzip             Gzip request body or not
+  -accept-encoding  Encodings advertised in Accept-Encoding, default "gzip, deflate, br, zstd", empty to disable
+  -charset          Charset of response body, like GBK, GB18030, Big5, default detected from Content-Type, XML prolog or HTML meta
+  -req-charset      Charset to encode the JSON/form request body and queries, like GBK
+  -proto            .proto file or descriptor set (protoc -o) to decode/encode application/x-protobuf
+  -proto-msg        Protobuf message name of response body, like pkg.Person
+  -proto-req-msg    Protobuf message name of request body, default same as -proto-msg
+  -req-codec        Encode the JSON request body as msgpack, cbor or protobuf
   -d                Download the url content as file, yes/n
+  -o                Save the body to file, - for stdout, placeholders: {n} {host} {status} {date} {time} {name} {ext}
+                    e.g. -o '{host}-{n}{ext}', -o 'dl/{name}'
+  -diff             Diff status, headers and JSON bodies of all the URLs against the first one, -c to fetch concurrently
+  -diff-ignore      Paths to ignore in -diff, repeatable or comma separated, like data.*.updatedAt,header.Server
+  -assert           Assert the response, repeatable, exit with code 3 on failures, e.g.
+                    -assert status==200 -assert header.Content-Type~json -assert body.data.count>0
+                    -assert time<500ms -assert size<1MB, operators: == != > >= < <= ~(regex) !~
+  -extract          Capture values from response for later requests (@name), repeatable, e.g.
+                    -extract token=data.token -extract rid=header:X-Request-Id -extract ver='regex:v(\d+)'
+                    @name refers the captured values or the ones in .env, @env.NAME refers the environment variables
+  -extract-save     Save the captured values to .env file
+  -junit            Write JUnit XML report of the workflow run, e.g. gurl run flow.yaml -junit report.xml
+  -session          Persist cookies, sticky headers and auth to ~/.config/gurl/sessions/NAME.json (or a path) for later runs,
+                    the headers and auth are bound to the host of the first request
+  -cookie           Read cookies from the Netscape/curl format cookie file, like curl -b cookies.txt
+  -cookie-jar       Write cookies to the Netscape/curl format cookie file after the requests, like curl -c cookies.txt
+  -follow           Follow redirects, default true, -follow=false to print the redirect response as it is
+  -max-redirs       Max number of redirects to follow, default 10
+  -keep-method      Keep the method and body for the redirect status codes, like -keep-method 301,302,303
+                    (by default, 301/302/303 change POST and others to GET, while 307/308 keep them)
+  -redirect-auth    Send Authorization to the redirect locations: domain (default, same domain or subdomains),
+                    origin (same scheme/host/port only), all (like curl --location-trusted) or none
+                    Redirects are shown with -pH/-ph for each hop, and the timing of each hop with -pt
+  -retry            Retry N times on the connection errors, timeouts and -retry-status codes, like -retry 3
+  -retry-status     Status codes to retry, default 429,503, like -retry-status 502,503,504
+  -retry-wait       Initial wait of the exponential backoff (with jitter), default 1s, Retry-After of the response is honored
+  -retry-max-wait   Max wait between the retries, default 30s
+  -retry-force      Retry the non-idempotent methods like POST and PATCH (without Idempotency-Key header) too
+  -openapi          Validate the request and response against the OpenAPI 3 spec, exit with code 3 on failures
+  -op               Fill in the method and path by the operationId of -openapi spec, path params {id} are taken from @id
+  -from-curl        Run the curl command line, like the one copied by browser devtools "Copy as cURL", e.g.
+                    gurl -from-curl 'curl -X POST -H "Content-Type: application/json" -d "{\"a\":1}" http://a.b/c'
+  -to-gurl          Print the equivalent gurl command of -from-curl instead of running it
+  -resolve          Resolve the host:port to the address, repeatable, like -resolve example.com:443:127.0.0.1
+  -postman-env      Postman environment file for gurl run api.postman_collection.json
+  -list             List the folders and requests of the Postman collection, e.g. gurl -list run api.postman_collection.json
+  -export           Print the equivalent client code of the request instead of sending it: curl, go, python or js
   -t                Timeout for read and write, default 1m
   -F filename       Upload a file, e.g. gurl :2110 -F 1.png -F 2.png
   -L limit          Limit rate /s, like 10K, append :req/:rsp to specific the limit direction
@@ -159,9 +302,15 @@ flags:
                        r: print JSON Raw format other than pretty
                        C: print items counting in colored output
                        N: disable proxy
+                       T: print JSON array of objects or CSV as table
+                       X: print body as hex dump, which is the default for binary body on terminal
                        o: print response option(like TLS)
                        a/A: HBhbsv
   -dns              Specified custom DNS resolver address, format: [DNS_SERVER]:[PORT]
+  -q expr           Query JSON response body before printing, e.g. -q 'data.items.#.name' (gjson path)
+                       or jq subset starting with . [ {, e.g. -q '.items[] | select(.age > 10) | {name, age}'
+  -table format     Table format for -pT, ascii (default), markdown or csv
+  -trace-file file  Record every byte sent and received on the connections (after TLS/TLCP decryption) to the file
   -version,v        Show Version Number
   -demo.env         Create a demo .env file
 METHOD:
@@ -174,6 +323,7 @@ ITEM:
                  Force query: key==value key==@/path/file
                  JSON data  : key:=value Upload: key@/path/file
                  File content as body: @/path/file
+                 InfluxDB line protocol: 'measurement,tag=v field=v [timestamp]'
 Example:
   gurl beego.me
   gurl :8080
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/man"
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// isBinary tells whether the body is binary, which should not be printed to the terminal directly.
+func isBinary(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+
+	if bytes.IndexByte(body[:min(len(body), 512)], 0) >= 0 {
+		return true
+	}
+
+	ct := http.DetectContentType(body)
+	return !ss.ContainsFold(ct, "text/", "json", "xml", "javascript")
+}
+
+// hexDump dumps the body in xxd style, like:
+// 00000000: 8950 4e47 0d0a 1a0a 0000 000d 4948 4452  .PNG........IHDR
+func hexDump(body []byte) string {
+	var out strings.Builder
+	out.WriteString(Color(fmt.Sprintf("# %s, %s (%d bytes)", http.DetectContentType(body),
+		man.Bytes(uint64(len(body))), len(body)), Gray))
+
+	for offset := 0; offset < len(body); offset += 16 {
+		line := body[offset:min(offset+16, len(body))]
+		out.WriteString("\n")
+		out.WriteString(Color(fmt.Sprintf("%08x:", offset), Gray))
+		for i := 0; i < 16; i++ {
+			if i%2 == 0 {
+				out.WriteByte(' ')
+			}
+			if i < len(line) {
+				out.WriteString(Color(fmt.Sprintf("%02x", line[i]), hexByteColor(line[i])))
+			} else {
+				out.WriteString("  ")
+			}
+		}
+		out.WriteString("  ")
+		for _, c := range line {
+			s := "."
+			if c >= 0x20 && c < 0x7f {
+				s = string(rune(c))
+			}
+			out.WriteString(Color(s, hexByteColor(c)))
+		}
+	}
+
+	return out.String()
+}
+
+// hexByteColor colors NUL gray, printable ASCII green, whitespace yellow and others red.
+func hexByteColor(c byte) uint8 {
+	switch {
+	case c == 0:
+		return Gray
+	case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		return Yellow
+	case c > 0x20 && c < 0x7f:
+		return Green
+	}
+	return Red
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestHexDump(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR\x00")
+	if !isBinary(png) {
+		t.Error("png should be binary")
+	}
+	for _, text := range []string{`{"a":1}`, "<a>b</a>", "\xc4\xe3\xba\xc3"} {
+		if isBinary([]byte(text)) {
+			t.Errorf("%q should not be binary", text)
+		}
+	}
+
+	want := "# image/png, 17B (17 bytes)\n" +
+		"00000000: 8950 4e47 0d0a 1a0a 0000 000d 4948 4452  .PNG........IHDR\n" +
+		"00000010: 00                                       ."
+	if got := hexDump(png); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+const (
+	// historyMaxBody is the max size of the request body to be recorded in the history.
+	historyMaxBody = 64 * 1024
+	// historyListSize is the number of the latest entries listed by gurl history.
+	historyListSize = 30
+	redacted        = "***"
+)
+
+// HistoryEntry is an invocation recorded in ~/.local/share/gurl/history.jsonl, with the secrets redacted.
+type HistoryEntry struct {
+	ID        int               `json:"id"`
+	Time      time.Time         `json:"time"`
+	Dir       string            `json:"dir,omitempty"`
+	Args      []string          `json:"args"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body,omitempty"`
+	Status    int               `json:"status,omitempty"`
+	Size      int               `json:"size,omitempty"`
+	ElapsedMs int64             `json:"elapsedMs,omitempty"`
+}
+
+var (
+	// historyArgs is the args of the invocation to be recorded, the replayed ones with the overrides for gurl replay.
+	historyArgs     = os.Args[1:]
+	historyRecorded atomic.Bool
+
+	safeArgReg       = regexp.MustCompile(`^[\w@%+=:,./\-]+$`)
+	sensitiveKeyReg  = regexp.MustCompile(`(?i)^(proxy-)?authorization$|(^|[-_])auth([-_]|$)|token|secret|passw|api[-_]?key|access[-_]?key|cookie|credential`)
+	sensitiveJSONReg = regexp.MustCompile(`("[\w\-]*(?i:token|secret|passw|api[-_]?key|access[-_]?key|credential)[\w\-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	sensitiveFormReg = regexp.MustCompile(`((?:^|[?&])[\w\-]*(?i:token|secret|passw|api[-_]?key|access[-_]?key|credential)[\w\-]*=)[^&]*`)
+)
+
+// historyFile returns the history file, $GURL_HISTORY=off to disable it, or a path to change it.
+func historyFile() string {
+	switch f := os.Getenv("GURL_HISTORY"); strings.ToLower(f) {
+	case "off", "0", "false", "no":
+		return ""
+	case "":
+	default:
+		return f
+	}
+
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "gurl", "history.jsonl")
+}
+
+// historyRecorder returns the func to record the first request of the invocation after it is done.
+func historyRecorder(req *Request) func() {
+	file := historyFile()
+	if file == "" || req.DryRequest || !historyRecorded.CompareAndSwap(false, true) {
+		return func() {}
+	}
+
+	var body []byte
+	if r := req.Req; r.Body != nil && r.ContentLength > 0 && r.ContentLength <= historyMaxBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			log.Fatalf("read request body failed: %v", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return func() {
+		e := &HistoryEntry{
+			Time:    time.Now(),
+			Args:    redactArgs(historyArgs),
+			Method:  req.Req.Method,
+			URL:     redactURL(req.Req.URL.String()),
+			Headers: map[string]string{},
+			Body:    redactText(string(body)),
+			Status:  req.resp.StatusCode,
+			Size:    len(req.rspBody),
+
+			ElapsedMs: req.rspElapsed.Milliseconds(),
+		}
+		e.Dir, _ = os.Getwd()
+		for k := range req.Req.Header {
+			if !strings.HasPrefix(k, "Gurl-") {
+				e.Headers[k] = redactValue(k, req.Req.Header.Get(k))
+			}
+		}
+		if err := appendHistory(file, e); err != nil {
+			log.Printf("record history to %s failed: %v", file, err)
+		}
+	}
+}
+
+func appendHistory(file string, e *HistoryEntry) error {
+	entries, err := loadHistory(file)
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func loadHistory(file string) (entries []*HistoryEntry, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if line := scanner.Bytes(); len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &e) == nil {
+			entries = append(entries, &e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func readHistory() (string, []*HistoryEntry) {
+	file := historyFile()
+	if file == "" {
+		log.Fatalf("history is disabled by GURL_HISTORY")
+	}
+	entries, err := loadHistory(file)
+	if err != nil {
+		log.Fatalf("read history %s failed: %v", file, err)
+	}
+	return file, entries
+}
+
+func findHistory(id string) *HistoryEntry {
+	file, entries := readHistory()
+	if id == "last" && len(entries) > 0 {
+		return entries[len(entries)-1]
+	}
+	for _, e := range entries {
+		if strconv.Itoa(e.ID) == id {
+			return e
+		}
+	}
+	log.Fatalf("history %s not found in %s", id, file)
+	return nil
+}
+
+// listHistory prints the latest entries matching all the keywords, or the details of the entry by gurl history ID.
+func listHistory(keywords []string) {
+	if len(keywords) == 1 && ss.IsDigits(keywords[0]) {
+		data, _ := json.MarshalIndent(findHistory(keywords[0]), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	_, entries := readHistory()
+	var matched []*HistoryEntry
+	for _, e := range entries {
+		if e.matches(keywords) {
+			matched = append(matched, e)
+		}
+	}
+	if n := len(matched) - historyListSize; n > 0 {
+		fmt.Printf("... %d earlier entries, search them by gurl history keywords\n", n)
+		matched = matched[n:]
+	}
+	for _, e := range matched {
+		fmt.Printf("%s %s %s %s %s %s\n", Color(fmt.Sprintf("#%d", e.ID), Magenta), e.Time.Format("2006-01-02 15:04:05"),
+			Color(strconv.Itoa(e.Status), Cyan), Color(e.Method, Green), e.URL, fmt.Sprintf("%d ms", e.ElapsedMs))
+		fmt.Printf("    gurl %s\n", quoteArgs(e.Args))
+	}
+}
+
+func (e *HistoryEntry) matches(keywords []string) bool {
+	s := strings.ToLower(strings.Join(append([]string{e.Method, e.URL, strconv.Itoa(e.Status)}, e.Args...), " "))
+	for _, k := range keywords {
+		if !strings.Contains(s, strings.ToLower(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+// replayHistory parses the args of the history entry, followed by the overrides in the command line, like
+// gurl replay 12 -pb name=bingoo, the override URL, method or items (with the same key) replace the recorded ones.
+func replayHistory(id string) []string {
+	e := findHistory(id)
+
+	overrides := os.Args[1:]
+	if i := slices.Index(overrides, "replay"); i >= 0 && i+1 < len(overrides) {
+		overrides = append(overrides[:i:i], overrides[i+2:]...)
+	}
+	args := e.Args
+	if wd, _ := os.Getwd(); e.Dir != "" && e.Dir != wd {
+		args = resolveHistoryFiles(e.Dir, args)
+		log.Printf("history #%d is recorded in %s, the relative files are resolved against it", e.ID, e.Dir)
+	}
+	historyArgs = append(append([]string{}, args...), overrides...)
+
+	fmt.Println(Color(fmt.Sprintf("### replay #%d: gurl %s", e.ID, quoteArgs(historyArgs)), Magenta))
+	if strings.Contains(strings.Join(historyArgs, " "), redacted) {
+		log.Printf("history #%d has the redacted values %s, override them like Authorization:xxx", e.ID, redacted)
+	}
+
+	recorded := reparseArgs(args)
+	return mergeReplayArgs(recorded, reparseArgs(overrides))
+}
+
+// resolveHistoryFiles resolves the relative files in the recorded args against the recorded dir, like
+// -b @body.json, -F a.txt, name=@a.txt, file@a.txt and run api.http, the not existing ones (like @var) are kept.
+func resolveHistoryFiles(dir string, args []string) []string {
+	resolve := func(f string) string {
+		if p := filepath.Join(dir, f); f != "" && !filepath.IsAbs(f) {
+			if _, exists, _ := Stat(p); exists {
+				return p
+			}
+		}
+		return f
+	}
+	resolveAt := func(v string) string {
+		if strings.HasPrefix(v, "@") {
+			return "@" + resolve(v[1:])
+		}
+		return v
+	}
+
+	result := make([]string, len(args))
+	for i, arg := range args {
+		prev := ""
+		if i > 0 {
+			prev = args[i-1]
+		}
+		subs := keyReg.FindStringSubmatch(arg)
+		switch {
+		case ss.AnyOf(prev, "-F", "--F") || prev == "run" && i == 1:
+			result[i] = resolve(arg)
+		case ss.AnyOf(prev, "-b", "--b", "-body", "--body"):
+			result[i] = resolveAt(arg)
+		case ss.HasPrefix(arg, "-F=", "--F="):
+			k, v, _ := strings.Cut(arg, "=")
+			result[i] = k + "=" + resolve(v)
+		case ss.HasPrefix(arg, "-b=", "--b=", "-body=", "--body="):
+			k, v, _ := strings.Cut(arg, "=")
+			result[i] = k + "=" + resolveAt(v)
+		case strings.HasPrefix(arg, "-") || len(subs) == 0:
+			result[i] = arg
+		case subs[2] == "@": // file@a.txt;type=text/plain, or @body.json
+			f, typ, ok := strings.Cut(subs[3], ";")
+			result[i] = subs[1] + "@" + resolve(f) + ss.If(ok, ";"+typ, "")
+		case subs[1] != "":
+			result[i] = subs[1] + subs[2] + resolveAt(subs[3])
+		default:
+			result[i] = arg
+		}
+	}
+	return result
+}
+
+// mergeReplayArgs removes the recorded URL, method and items which are overridden.
+func mergeReplayArgs(recorded, overrides []string) []string {
+	var merged []string
+	for _, r := range recorded {
+		if !overridden(r, overrides) {
+			merged = append(merged, r)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+func overridden(arg string, overrides []string) bool {
+	kind := argKind(arg)
+	for _, o := range overrides {
+		if argKind(o) != kind {
+			continue
+		}
+		switch kind {
+		case "url", "method":
+			return true
+		case ":": // headers
+			if strings.EqualFold(itemKey(o), itemKey(arg)) {
+				return true
+			}
+		case "==", "=", ":=", "@":
+			if itemKey(o) == itemKey(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// argKind returns the kind of the positional arg, command, url, method, line, or the operator of the item like : == = := @.
+func argKind(arg string) string {
+	if arg == "version" || arg == "run" {
+		return "command"
+	}
+	if ss.HasPrefix(arg, "http://", "https://") {
+		return "url"
+	}
+	if inSlice(strings.ToUpper(arg), methodList) {
+		return "method"
+	}
+	if isLineProtocol(arg) {
+		return "line"
+	}
+	subs := keyReg.FindStringSubmatch(arg)
+	if len(subs) == 0 || subs[1] == "" || subs[2] == ":" && strings.Contains(subs[1], ".") {
+		return "url"
+	}
+	return subs[2]
+}
+
+func itemKey(arg string) string {
+	if subs := keyReg.FindStringSubmatch(arg); len(subs) > 0 {
+		return subs[1]
+	}
+	return ""
+}
+
+// redactArgs redacts the -auth value and the sensitive items in the args.
+func redactArgs(args []string) []string {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && ss.AnyOf(args[i-1], "-auth", "--auth", "-A", "--A"):
+			result[i] = redactAuth(arg)
+		case ss.HasPrefix(arg, "-auth=", "--auth=", "-A=", "--A="):
+			k, v, _ := strings.Cut(arg, "=")
+			result[i] = k + "=" + redactAuth(v)
+		case i > 0 && ss.AnyOf(args[i-1], "-url", "--url", "-u", "--u"):
+			result[i] = redactURL(arg)
+		case ss.HasPrefix(arg, "-url=", "--url=", "-u=", "--u="):
+			k, v, _ := strings.Cut(arg, "=")
+			result[i] = k + "=" + redactURL(v)
+		case !strings.HasPrefix(arg, "-") && argKind(arg) == "url":
+			result[i] = redactURL(arg)
+		default:
+			if subs := keyReg.FindStringSubmatch(arg); len(subs) > 0 && subs[1] != "" && !strings.HasPrefix(arg, "-") {
+				result[i] = subs[1] + subs[2] + redactValue(subs[1], subs[3])
+			} else {
+				result[i] = redactText(arg)
+			}
+		}
+	}
+	return result
+}
+
+func redactAuth(v string) string {
+	if user, _, ok := strings.Cut(v, ":"); ok {
+		return user + ":" + redacted
+	}
+	return redacted
+}
+
+// redactValue redacts the value of the sensitive key, except the references like @token or ${TOKEN}.
+func redactValue(key, value string) string {
+	if value != "" && sensitiveKeyReg.MatchString(key) && !ss.HasPrefix(value, "@", "$") {
+		return redacted
+	}
+	return redactText(value)
+}
+
+// redactURL redacts the password (or the token as the user) in the userinfo, and the sensitive query values.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" && u.User == nil {
+		return redactText(s)
+	}
+
+	userinfo := ""
+	if u.User != nil {
+		userinfo = redacted
+		if _, ok := u.User.Password(); ok {
+			userinfo = url.User(u.User.Username()).String() + ":" + redacted
+		}
+		u.User = nil
+	}
+	queries := strings.Split(u.RawQuery, "&")
+	for i, q := range queries {
+		if k, v, ok := strings.Cut(q, "="); ok && v != "" {
+			if key, err := url.QueryUnescape(k); err == nil && sensitiveKeyReg.MatchString(key) {
+				queries[i] = k + "=" + redacted
+			}
+		}
+	}
+	u.RawQuery = strings.Join(queries, "&")
+
+	s = u.String()
+	if userinfo != "" {
+		s = strings.Replace(s, "//", "//"+userinfo+"@", 1)
+	}
+	return s
+}
+
+// redactText redacts the sensitive fields in the JSON or the form.
+func redactText(s string) string {
+	s = sensitiveJSONReg.ReplaceAllString(s, `$1"`+redacted+`"`)
+	return sensitiveFormReg.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// quoteArgs joins the args to be copied into the shell, only the ones with special chars are quoted.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ss.If(safeArgReg.MatchString(arg), arg, shQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{
+		"-auth", "admin:123", "-A=root:456", "POST", ":8080/login", "Authorization:Bearer xyz", "X-Token:@token",
+		"password=abc", "name=bingoo", "api_key==k1", `-b`, `{"user":"a","accessKey":"s\"x","n":1}`, "a==1&secret=2",
+	}
+	want := []string{
+		"-auth", "admin:***", "-A=root:***", "POST", ":8080/login", "Authorization:***", "X-Token:@token",
+		"password=***", "name=bingoo", "api_key==***", `-b`, `{"user":"a","accessKey":"***","n":1}`, "a==1&secret=***",
+	}
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs() = %q\nwant %q", got, want)
+	}
+
+	args = []string{"-u", "http://h/p?access_token=abc", "--url=https://u:p@h/p", "https://tk@h/p?id=1&Api-Key=k", "a.b:8080/p?q=1&token=t"}
+	want = []string{"-u", "http://h/p?access_token=***", "--url=https://u:***@h/p", "https://***@h/p?id=1&Api-Key=***", "a.b:8080/p?q=1&token=***"}
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs() = %q\nwant %q", got, want)
+	}
+
+	args = []string{"author=bob", "X-Author:bob", "authorized==1", "X-Auth:a", "Proxy-Authorization:Basic x", "auth_code=c", "http://h/p?author=bob&auth=x"}
+	want = []string{"author=bob", "X-Author:bob", "authorized==1", "X-Auth:***", "Proxy-Authorization:***", "auth_code=***", "http://h/p?author=bob&auth=***"}
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs() = %q\nwant %q", got, want)
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	cases := map[string]string{
+		"http://h/p?token=abc&x=1&api_key=k": "http://h/p?token=***&x=1&api_key=***",
+		"token=abc&x=1":                      "token=***&x=1",
+		`{"password":"p","x":1}`:             `{"password":"***","x":1}`,
+	}
+	for s, want := range cases {
+		if got := redactText(s); got != want {
+			t.Errorf("redactText(%s) = %s, want %s", s, got, want)
+		}
+	}
+
+	cases = map[string]string{
+		"http://user:pass@h:8080/p?token=abc&x=1&api_key=k#f": "http://user:***@h:8080/p?token=***&x=1&api_key=***#f",
+		"https://h/p?x=1&Authorization=":                      "https://h/p?x=1&Authorization=",
+		"http://h/%E5%90%8D?access%5Ftoken=a":                 "http://h/%E5%90%8D?access%5Ftoken=***",
+	}
+	for s, want := range cases {
+		if got := redactURL(s); got != want {
+			t.Errorf("redactURL(%s) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestResolveHistoryFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a.json", "api.http"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := filepath.Join(dir, "a.json")
+
+	args := []string{"-b", "@a.json", "-F=a.json", "x:=@a.json", "f@a.json;type=text/plain", "@a.json", "t==@token", "k=@/abs/a.json", ":8080/a.json"}
+	want := []string{"-b", "@" + a, "-F=" + a, "x:=@" + a, "f@" + a + ";type=text/plain", "@" + a, "t==@token", "k=@/abs/a.json", ":8080/a.json"}
+	if got := resolveHistoryFiles(dir, args); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveHistoryFiles() = %q\nwant %q", got, want)
+	}
+	if got := resolveHistoryFiles(dir, []string{"run", "api.http", "a.json"}); !reflect.DeepEqual(got, []string{"run", filepath.Join(dir, "api.http"), "a.json"}) {
+		t.Errorf("resolveHistoryFiles() = %q", got)
+	}
+}
+
+func TestMergeReplayArgs(t *testing.T) {
+	recorded := []string{"POST", ":8080/users", "Authorization:***", "name=a", "age:=1", "q==1"}
+	overrides := []string{"authorization:Bearer new", "name=b", "http://a.b/users"}
+	want := []string{"POST", "age:=1", "q==1", "authorization:Bearer new", "name=b", "http://a.b/users"}
+	if got := mergeReplayArgs(recorded, overrides); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeReplayArgs() = %q\nwant %q", got, want)
+	}
+
+	recorded = []string{"run", "api.http", "login"}
+	if got := mergeReplayArgs(recorded, []string{"PUT"}); !reflect.DeepEqual(got, []string{"run", "api.http", "login", "PUT"}) {
+		t.Errorf("mergeReplayArgs() = %q", got)
+	}
+}
+
+func TestAppendHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	t.Setenv("GURL_HISTORY", file)
+	if historyFile() != file {
+		t.Fatalf("historyFile() = %s", historyFile())
+	}
+
+	for _, u := range []string{"http://a/1", "http://a/2"} {
+		if err := appendHistory(file, &HistoryEntry{Method: "GET", URL: u, Args: []string{u}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := loadHistory(file)
+	if err != nil || len(entries) != 2 || entries[1].ID != 2 || findHistory("last").URL != "http://a/2" || findHistory("1").URL != "http://a/1" {
+		t.Errorf("entries = %v, err = %v", entries, err)
+	}
+	if !entries[0].matches([]string{"get", "A/1"}) || entries[0].matches([]string{"a/2"}) {
+		t.Error("matches mismatched")
+	}
+
+	t.Setenv("GURL_HISTORY", "off")
+	if historyFile() != "" {
+		t.Error("history should be disabled")
+	}
+}
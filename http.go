@@ -37,13 +37,16 @@ func getHTTP(method, url string, args []string, timeout time.Duration) (r *Reque
 	r.Setting.ConnectTimeout = timeout
 	r.DryRequest = strings.HasPrefix(url, DryRequestURL)
 	r.Timeout = timeout
-	r.Header("Accept-Encoding", "gzip, deflate")
+	if acceptEncoding != "" {
+		r.Header("Accept-Encoding", acceptEncoding)
+	}
 	if form || method == "GET" {
 		r.Header("Accept", "*/*")
 	} else {
 		r.Header("Accept", "application/json")
 	}
 	r.Header("Gurl-Date", time.Now().UTC().Format(http.TimeFormat))
+	var lines []string
 	// https://httpie.io/docs#request-items
 	// Item Type	Description
 	// HTTP Headers Name:Value	Arbitrary HTTP header, e.g. X-API-Token:123
@@ -53,6 +56,11 @@ func getHTTP(method, url string, args []string, timeout time.Duration) (r *Reque
 	// File upload fields field@/dir/file, field@file;type=mime	Only available with --form, -f and --multipart. For example screenshot@~/Pictures/img.png, or 'cv@cv.txt;type=text/markdown'. With --form, the presence of a file field results in a --multipart request
 	for i := range args {
 		arg := args[i]
+		if isLineProtocol(arg) {
+			lines = append(lines, arg)
+			continue
+		}
+
 		subs := keyReg.FindStringSubmatch(arg)
 		if len(subs) == 0 {
 			continue
@@ -77,7 +85,14 @@ func getHTTP(method, url string, args []string, timeout time.Duration) (r *Reque
 				jsonmap[k] = json.RawMessage(dat)
 			}
 		case "==": // Queries
-			r.Query(k, tryReadFile(val))
+			if refersCapturedVar(val) {
+				if r.evalQueries == nil {
+					r.evalQueries = map[string]string{}
+				}
+				r.evalQueries[k] = val
+			} else {
+				r.Query(k, tryReadFile(val))
+			}
 		case "=": // Params
 			if val = tryReadFile(val); form || method == "GET" {
 				r.Param(k, val) // As Query parameter,
@@ -85,8 +100,16 @@ func getHTTP(method, url string, args []string, timeout time.Duration) (r *Reque
 				jsonmap[k] = val // body will be eval later
 			}
 		case ":": // Headers
+			if k != "Host" {
+				r.itemHeaders = append(r.itemHeaders, http.CanonicalHeaderKey(k))
+			}
 			if k == "Host" {
 				r.SetHost(val)
+			} else if refersCapturedVar(val) {
+				if r.evalHeaders == nil {
+					r.evalHeaders = map[string]string{}
+				}
+				r.evalHeaders[k] = val
 			} else {
 				if strings.EqualFold(k, "Accept") && strings.EqualFold(val, "JSON") {
 					r.Header("Accept", "application/json")
@@ -102,6 +125,10 @@ func getHTTP(method, url string, args []string, timeout time.Duration) (r *Reque
 			}
 		}
 	}
+	if len(lines) > 0 { // InfluxDB line protocol write body
+		r.Header("Content-Type", "text/plain; charset=utf-8")
+		r.Body(strings.Join(lines, "\n"))
+	}
 	if !form && len(jsonmap) > 0 {
 		r.Body(jsonmap)
 	}
@@ -155,7 +182,15 @@ func formatResponseBody(r *Request, pretty, ugly, freeInnerJSON, influxDB bool)
 		log.Fatalln("can't get the url", err)
 	}
 
-	if influxTablePrint(ugly, influxDB, dat) {
+	if jsonQuery != "" {
+		return formatQueryResult(dat, pretty, ugly, freeInnerJSON)
+	}
+
+	if influxTablePrint(ugly, influxDB, dat, r.resp.Header.Get("Content-Type")) {
+		return ""
+	}
+
+	if HasPrintOption(printTable) && tablePrint(dat, r.resp.Header.Get("Content-Type")) {
 		return ""
 	}
 
@@ -166,6 +201,24 @@ func formatResponseBody(r *Request, pretty, ugly, freeInnerJSON, influxDB bool)
 	return formatBytes(dat, pretty, ugly, freeInnerJSON)
 }
 
+func formatQueryResult(dat []byte, pretty, ugly, freeInnerJSON bool) string {
+	results, err := queryJSON(dat, jsonQuery)
+	if err != nil {
+		log.Printf("query %q failed: %v", jsonQuery, err)
+		return formatBytes(dat, pretty, ugly, freeInnerJSON)
+	}
+
+	if HasPrintOption(printTable) && jsonTablePrint(results) {
+		return ""
+	}
+
+	lines := make([]string, len(results))
+	for i, result := range results {
+		lines[i] = formatBytes(result, pretty, ugly, freeInnerJSON)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func saveTempFile(dat []byte, envName string, ugly bool) bool {
 	if ugly {
 		return false
@@ -183,9 +236,22 @@ func saveTempFile(dat []byte, envName string, ugly bool) bool {
 }
 
 func formatBytes(body []byte, pretty, ugly, freeInnerJSON bool) string {
+	// binary bodies are dumped as hex only to the terminal, and kept raw when redirected, like gurl ... > a.png.
+	if HasPrintOption(printHex) || hasStdoutDevice && isBinary(body) {
+		return hexDump(body)
+	}
+
 	body = bytes.TrimSpace(body)
 	isJSON := jj.ParseBytes(body).IsJSON()
 
+	if !isJSON && isMarkup(body) {
+		body = formatMarkup(body, pretty, ugly)
+		if hasStdoutDevice {
+			return ColorfulMarkup(string(body))
+		}
+		return string(body)
+	}
+
 	if isJSON {
 		if freeInnerJSON {
 			body = jj.FreeInnerJSON(body)
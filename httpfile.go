@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// HTTPFileRequest is a request in the .http file of VS Code REST Client / JetBrains HTTP Client format.
+type HTTPFileRequest struct {
+	Name    string
+	Method  string
+	URL     string
+	Headers []string
+	Body    string
+}
+
+type httpFileResponse struct {
+	Header http.Header
+	Body   []byte
+}
+
+var (
+	httpFileVarReg     = regexp.MustCompile(`^@([\w.\-]+)\s*=\s*(.*)$`)
+	httpFileNameReg    = regexp.MustCompile(`^(?:#|//)\s*@name\s*[= ]\s*([\w.\-]+)`)
+	httpFileInterpReg  = regexp.MustCompile(`\{\{\s*(.+?)\s*}}`)
+	httpFileRequestReg = regexp.MustCompile(`^(?:(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|CONNECT|TRACE)\s+)?(\S+)(?:\s+HTTP/[\d.]+)?$`)
+)
+
+// parseHTTPFile parses the .http file into the file level variables (@var = value) and the ### separated requests.
+func parseHTTPFile(data string) (map[string]string, []*HTTPFileRequest) {
+	vars := map[string]string{}
+	var requests []*HTTPFileRequest
+
+	var r *HTTPFileRequest
+	var body []string
+	inBody := false
+	title := ""
+	flush := func() {
+		if r != nil {
+			r.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			r.Name = ss.Or(r.Name, title)
+			requests = append(requests, r)
+		}
+		r, body, inBody, title = nil, nil, false, ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "###"):
+			flush()
+			title = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		case inBody:
+			body = append(body, line)
+			continue
+		case trimmed == "":
+			if r != nil && r.URL != "" {
+				inBody = true
+			}
+			continue
+		}
+
+		if subs := httpFileNameReg.FindStringSubmatch(trimmed); len(subs) > 0 {
+			if r == nil {
+				r = &HTTPFileRequest{}
+			}
+			r.Name = subs[1]
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if r == nil || r.URL == "" {
+			if subs := httpFileVarReg.FindStringSubmatch(trimmed); len(subs) > 0 {
+				vars[subs[1]] = subs[2]
+				continue
+			}
+		}
+
+		switch {
+		case r == nil || r.URL == "":
+			subs := httpFileRequestReg.FindStringSubmatch(trimmed)
+			if len(subs) == 0 {
+				log.Fatalf("bad request line %q", trimmed)
+			}
+			if r == nil {
+				r = &HTTPFileRequest{}
+			}
+			r.Method, r.URL = ss.Or(subs[1], http.MethodGet), subs[2]
+		case ss.HasPrefix(trimmed, "?", "&"): // query continuation lines
+			r.URL += trimmed
+		default:
+			if k, v, ok := strings.Cut(trimmed, ":"); ok {
+				r.Headers = append(r.Headers, strings.TrimSpace(k)+":"+strings.TrimSpace(v))
+			}
+		}
+	}
+	flush()
+
+	return vars, requests
+}
+
+// httpFileBody converts the REST Client file body `< ./body.json` to gurl's @./body.json.
+func httpFileBody(b string) string {
+	if strings.HasPrefix(b, "<") && !strings.Contains(b, "\n") {
+		return "@" + strings.TrimSpace(b[1:])
+	}
+	return b
+}
+
+// HTTPFileRunner runs the requests in the .http file.
+type HTTPFileRunner struct {
+	Vars      map[string]string
+	Responses map[string]*httpFileResponse
+	Requests  []*HTTPFileRequest
+}
+
+// interpolate replaces {{var}}, {{$uuid}}, {{$processEnv NAME}} and {{login.response.body.$.token}} in s.
+func (h *HTTPFileRunner) interpolate(s string, depth int) string {
+	return httpFileInterpReg.ReplaceAllStringFunc(s, func(m string) string {
+		expr := httpFileInterpReg.FindStringSubmatch(m)[1]
+		if v, ok := h.resolve(expr, depth); ok {
+			return v
+		}
+		return m
+	})
+}
+
+func (h *HTTPFileRunner) resolve(expr string, depth int) (string, bool) {
+	if depth > 10 {
+		log.Fatalf("too deep variable references of %s", expr)
+	}
+
+	if strings.HasPrefix(expr, "$") {
+		return h.resolveSystem(expr)
+	}
+	if v, ok := h.Vars[expr]; ok {
+		return h.interpolate(v, depth+1), true
+	}
+	if name, path, ok := strings.Cut(expr, ".response."); ok {
+		if _, found := h.Responses[name]; !found {
+			h.sendNamed(name, expr)
+		}
+		return h.resolveResponse(h.Responses[name], path)
+	}
+	if v, ok := valuer.Map[expr]; ok {
+		return fmt.Sprintf("%v", v), true
+	}
+	return os.LookupEnv(expr)
+}
+
+func (h *HTTPFileRunner) resolveSystem(expr string) (string, bool) {
+	fields := strings.Fields(expr)
+	switch fields[0] {
+	case "$processEnv", "$dotenv":
+		if len(fields) > 1 {
+			return os.Getenv(strings.TrimPrefix(fields[1], "%")), true
+		}
+	case "$guid":
+		v, err := Eval("@uuid")
+		return v, err == nil
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "$randomInt":
+		if len(fields) == 3 {
+			lo, _ := strconv.Atoi(fields[1])
+			hi, _ := strconv.Atoi(fields[2])
+			if hi > lo {
+				return strconv.Itoa(lo + rand.Intn(hi-lo)), true
+			}
+		}
+	default: // gurl generators like {{$uuid}}, {{$random_int}}
+		v, err := Eval("@" + strings.TrimPrefix(expr, "$"))
+		return v, err == nil
+	}
+	return "", false
+}
+
+// resolveResponse resolves body.$.a.b, body.a.b, body.* or headers.X of the named response.
+func (h *HTTPFileRunner) resolveResponse(rsp *httpFileResponse, path string) (string, bool) {
+	switch {
+	case strings.HasPrefix(path, "headers."):
+		return rsp.Header.Get(strings.TrimPrefix(path, "headers.")), true
+	case path == "body" || path == "body.*" || path == "body.$":
+		return string(rsp.Body), true
+	case strings.HasPrefix(path, "body."):
+		p := strings.TrimPrefix(strings.TrimPrefix(path, "body."), "$.")
+		p = strings.NewReplacer("[", ".", "]", "").Replace(p)
+		results, err := queryJSON(rsp.Body, p)
+		if err != nil || len(results) == 0 {
+			return "", false
+		}
+		return jsonScalar(results[0]), true
+	}
+	return "", false
+}
+
+// runHTTPFile runs the requests, or the named ones, in the .http file, like gurl run api.http login.
+func runHTTPFile(filename string, names []string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Fatalf("read %s failed: %v", filename, err)
+	}
+
+	vars, requests := parseHTTPFile(string(data))
+	h := &HTTPFileRunner{Vars: vars, Responses: map[string]*httpFileResponse{}, Requests: requests}
+	for _, r := range requests {
+		if _, sent := h.Responses[r.Name]; sent && r.Name != "" {
+			continue // sent already as referred by the former requests
+		}
+		if len(names) == 0 || ss.AnyOf(r.Name, names...) {
+			h.send(r)
+		}
+	}
+}
+
+// sendNamed sends the named request, which is referred by expr but not sent yet.
+func (h *HTTPFileRunner) sendNamed(name, expr string) {
+	for _, r := range h.Requests {
+		if r.Name == name {
+			h.send(r)
+			return
+		}
+	}
+	log.Fatalf("request %s referred by %s not found", name, expr)
+}
+
+func (h *HTTPFileRunner) send(r *HTTPFileRequest) {
+	if r.Name != "" {
+		h.Responses[r.Name] = &httpFileResponse{} // avoid cyclic references
+	}
+	// interpolate first, because the referred requests may be sent during interpolation.
+	u, b := h.interpolate(r.URL, 0), h.interpolate(r.Body, 0)
+	headers := make([]string, len(r.Headers))
+	for i, header := range r.Headers {
+		headers[i] = h.interpolate(header, 0)
+	}
+
+	fmt.Println(Color("### "+ss.Or(r.Name, r.Method+" "+r.URL), Magenta))
+	defer func(m, b string) { method, body = m, b }(method, body)
+	// the body is sent as it is, only {{var}} is expanded, except the file like < ./body.json.
+	method, body = r.Method, ""
+	if f := httpFileBody(b); f != b {
+		body = f
+	}
+	req, addrGen := prepareRequest(u, headers, nil, func(req *Request) {
+		if body == "" && b != "" {
+			req.BodyRaw(b)
+		}
+	})
+	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
+	if err := doRequest(req, addrGen); err != nil {
+		log.Fatalf("request %s failed: %v", r.Name, err)
+	}
+	rsp := &httpFileResponse{}
+	if req.resp != nil {
+		rsp.Header, rsp.Body = req.resp.Header, req.rspBody
+	}
+	if r.Name != "" {
+		h.Responses[r.Name] = rsp
+	}
+	fmt.Println()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseHTTPFile(t *testing.T) {
+	vars, requests := parseHTTPFile(`@host = http://localhost:8080
+@user = bingoo
+
+### Login
+# @name login
+POST {{host}}/login HTTP/1.1
+Content-Type: application/json
+
+{"user": "{{user}}"}
+
+###
+GET {{host}}/me
+    ?a=1
+    &b=2
+Authorization: Bearer {{login.response.body.$.data.token}}
+
+### upload
+PUT {{host}}/upload
+
+< ./body.json
+`)
+
+	if want := map[string]string{"host": "http://localhost:8080", "user": "bingoo"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("got vars %v", vars)
+	}
+	want := []*HTTPFileRequest{
+		{Name: "login", Method: "POST", URL: "{{host}}/login", Headers: []string{"Content-Type:application/json"}, Body: `{"user": "{{user}}"}`},
+		{Method: "GET", URL: "{{host}}/me?a=1&b=2", Headers: []string{"Authorization:Bearer {{login.response.body.$.data.token}}"}},
+		{Name: "upload", Method: "PUT", URL: "{{host}}/upload", Body: "< ./body.json"},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		for _, r := range requests {
+			t.Errorf("got %+v", r)
+		}
+	}
+
+	h := &HTTPFileRunner{Vars: vars, Responses: map[string]*httpFileResponse{
+		"login": {Header: http.Header{"X-Id": {"1"}}, Body: []byte(`{"data":{"token":"abc","list":[1,2]}}`)},
+	}}
+	cases := map[string]string{
+		requests[1].Headers[0]:                     "Authorization:Bearer abc",
+		"{{host}}/{{login.response.headers.X-Id}}": "http://localhost:8080/1",
+		"{{login.response.body.$.data.list[1]}}":   "2",
+		"{{unknown}}":                              "{{unknown}}",
+	}
+	for s, want := range cases {
+		if got := h.interpolate(s, 0); got != want {
+			t.Errorf("%s: got %s, want %s", s, got, want)
+		}
+	}
+	if got := httpFileBody("< ./body.json"); got != "@./body.json" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestRunHTTPFile(t *testing.T) {
+	var logins, orders atomic.Int32
+	var signup []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/signup":
+			signup, _ = io.ReadAll(r.Body)
+		case "/login":
+			logins.Add(1)
+			_, _ = w.Write([]byte(`{"token":"abc"}`))
+		case "/orders":
+			if r.URL.Query().Get("token") == "abc" {
+				orders.Add(1)
+			}
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	fn := filepath.Join(t.TempDir(), "api.http")
+	_ = os.WriteFile(fn, []byte(`@host = `+srv.URL+`
+
+### orders
+GET {{host}}/orders?token={{login.response.body.$.token}}
+
+### login
+# @name login
+POST {{host}}/login
+
+### signup
+POST {{host}}/signup
+
+{"email": "user@example.com", "id": "@uuid", "host": "{{host}}"}
+`), 0o644)
+
+	method, body = "PUT", "x"
+	runHTTPFile(fn, nil)
+	if logins.Load() != 1 || orders.Load() != 1 {
+		t.Errorf("logins %d, orders %d, want 1, 1", logins.Load(), orders.Load())
+	}
+	if want := `{"email": "user@example.com", "id": "@uuid", "host": "` + srv.URL + `"}`; string(signup) != want {
+		t.Errorf("signup body got %s, want %s", signup, want)
+	}
+	if method != "PUT" || body != "x" {
+		t.Errorf("method %q and body %q are not restored", method, body)
+	}
+}
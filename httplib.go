@@ -15,7 +15,6 @@ import (
 	"mime/multipart"
 	"net"
 	"net/http"
-	"net/http/cookiejar"
 	"net/http/httptrace"
 	"net/http/httputil"
 	"net/url"
@@ -61,32 +60,25 @@ func NewRequest(rawURL, method string) *Request {
 }
 
 func (b *Request) SetupTransport() {
-	trans := b.Setting.Transport
-	if trans == nil { // create default transport
-		trans = &http.Transport{
-			TLSHandshakeTimeout: 10 * time.Second,
-			IdleConnTimeout:     10 * time.Second,
-		}
+	b.Req.Close = b.DisableKeepAlives
+	if b.Setting.Transport != nil { // the preset one is set up already, like the shared one of gurl -i
+		b.Transport = b.Setting.Transport
+		return
 	}
 
-	// if b.transport is *http.Transport then set the settings.
-	if t, ok := trans.(*http.Transport); ok {
-		if t.TLSClientConfig == nil {
-			t.TLSClientConfig = b.Setting.TLSConfig
-		}
-		if t.Proxy == nil {
-			t.Proxy = b.Setting.Proxy
-		}
-
-		t.DialContext = TimeoutDialer(b.Setting.ConnectTimeout, t.TLSClientConfig, b.debug, &b.readSum, &b.writeSum)
-		t.DialTLSContext = t.DialContext
+	// create default transport
+	trans := &http.Transport{
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     10 * time.Second,
 	}
 
+	trans.TLSClientConfig = b.Setting.TLSConfig
+	trans.Proxy = b.Setting.Proxy
+	trans.DialContext = TimeoutDialer(b.Setting.ConnectTimeout, trans.TLSClientConfig, b.debug, &b.readSum, &b.writeSum)
+	trans.DialTLSContext = trans.DialContext
+
 	// https://blog.witd.in/2019/02/25/golang-http-client-关闭重用连接两种方法/
-	if t, ok := trans.(*http.Transport); ok {
-		t.DisableKeepAlives = b.DisableKeepAlives
-	}
-	b.Req.Close = b.DisableKeepAlives
+	trans.DisableKeepAlives = b.DisableKeepAlives
 	b.Transport = trans
 }
 
@@ -114,6 +106,13 @@ type Request struct {
 
 	queries, params, files map[string]string
 
+	// evalHeaders and evalQueries are the headers and queries referring captured variables like @token,
+	// evaluated before each sending into the headers and evalQuery.
+	evalHeaders, evalQueries map[string]string
+	evalQuery                string
+	// itemHeaders are the names of the headers specified by the items like X-Tenant:t1, which are sticky in -session.
+	itemHeaders []string
+
 	cancelTimeout context.CancelFunc
 	timeResetCh   chan struct{}
 
@@ -132,6 +131,12 @@ type Request struct {
 	readSum  int64
 	writeSum int64
 
+	// rspWireSize is the size of response body on wire, before Content-Encoding decoded.
+	rspWireSize int64
+	// rspElapsed is the time elapsed from sending the request to reading the whole response body.
+	rspElapsed time.Duration
+	roundTrips []roundTrip
+
 	DryRequest bool
 
 	DisableKeepAlives bool
@@ -268,12 +273,27 @@ func (b *Request) BodyCh(data func() (string, error)) *Request {
 
 func (b *Request) evalBytes(data []byte) (io.ReadCloser, int64) {
 	eval, _ := Eval(string(data))
-	if jj.Valid(eval) {
-		b.Header("Content-Type", "application/json")
-	}
+	eval = b.encodeBody(eval)
 	return io.NopCloser(bytes.NewBufferString(eval)), int64(len(eval))
 }
 
+// encodeBody sets the Content-Type for JSON body, and encodes it by -req-codec or -req-charset.
+// Other bodies are sent as they are, the form body is encoded by createParamBody already.
+func (b *Request) encodeBody(eval string) string {
+	if !jj.Valid(eval) {
+		return eval
+	}
+
+	if reqCodec != "" {
+		d, contentType := encodeCodecBody([]byte(eval))
+		b.Header("Content-Type", contentType)
+		return string(d)
+	}
+
+	b.Header("Content-Type", withReqCharset("application/json"))
+	return fromUTF8(eval)
+}
+
 func (b *Request) BodyFileLines(t string) bool {
 	if strings.HasPrefix(t, "@") {
 		t = t[1:]
@@ -404,11 +424,16 @@ func (b *Request) BodyString(s string) {
 	if err != nil {
 		log.Fatalf("eval: %v", err)
 	}
+	eval = b.encodeBody(eval)
 	b.Req.Body = io.NopCloser(strings.NewReader(eval))
 	b.Req.ContentLength = int64(len(eval))
-	if jj.Valid(eval) {
-		b.Header("Content-Type", "application/json")
-	}
+}
+
+// BodyRaw sets the body as it is, without evaluating the @ variables, like the bodies of .http files and Postman.
+func (b *Request) BodyRaw(s string) *Request {
+	b.bodyData = nil
+	s = b.encodeBody(s)
+	return b.BodyAndSize(io.NopCloser(strings.NewReader(s)), int64(len(s)))
 }
 
 func appendURL(url, append string) string {
@@ -472,15 +497,48 @@ func (b *Request) BuildURL() {
 
 		// with params
 		if len(paramBody) > 0 {
-			b.Header("Content-Type", "application/x-www-form-urlencoded")
+			b.Header("Content-Type", withReqCharset("application/x-www-form-urlencoded"))
 			b.Body(paramBody)
 		}
 	}
 }
 
+// EvalItems evaluates the headers and queries referring captured variables, like Authorization:Bearer @token.
+func (b *Request) EvalItems() {
+	for k, v := range b.evalHeaders {
+		eval, err := Eval(v)
+		if err != nil {
+			log.Fatalf("eval header %s: %v", k, err)
+		}
+		b.Header(k, eval)
+	}
+
+	if len(b.evalQueries) == 0 {
+		return
+	}
+	queries := map[string]string{}
+	for k, v := range b.evalQueries {
+		eval, err := Eval(v)
+		if err != nil {
+			log.Fatalf("eval query %s: %v", k, err)
+		}
+		queries[k] = eval
+	}
+	b.evalQuery = createParamBody(queries)
+}
+
+// fullURL appends the queries to the URL.
+func (b *Request) fullURL(u string) string {
+	for _, q := range b.urlQuery {
+		u = appendURL(u, q)
+	}
+	return appendURL(u, b.evalQuery)
+}
+
 func (b *Request) Reset() {
 	b.resp.StatusCode = 0
 	b.rspBody = nil
+	b.rspWireSize = 0
 	if b.timeResetCh != nil {
 		select {
 		case b.timeResetCh <- struct{}{}:
@@ -514,9 +572,9 @@ func createParamBody(params map[string]string) string {
 	if len(params) > 0 {
 		var buf bytes.Buffer
 		for k, v := range params {
-			buf.WriteString(url.QueryEscape(k))
+			buf.WriteString(url.QueryEscape(fromUTF8(k)))
 			buf.WriteByte('=')
-			buf.WriteString(url.QueryEscape(v))
+			buf.WriteString(url.QueryEscape(fromUTF8(v)))
 			buf.WriteByte('&')
 		}
 		paramBody = buf.String()
@@ -530,17 +588,20 @@ func createParamBody(params map[string]string) string {
 // refer: Go HTTP Redirect的知识点总结 https://colobu.com/2017/04/19/go-http-redirect/
 type LogRedirects struct {
 	http.RoundTripper
+	req *Request
 }
 
-func (l LogRedirects) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+func (l *LogRedirects) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	t := l.RoundTripper
 	if t == nil {
 		t = http.DefaultTransport
 	}
+	start := time.Now()
 	resp, err = t.RoundTrip(req)
 	if err != nil {
 		return
 	}
+	l.record(req, resp, time.Since(start))
 	if isRedirect(resp.StatusCode) && HasPrintOption(printVerbose) {
 		log.Printf("FROM %s", req.URL)
 		log.Printf("Redirect(%d) to %s", resp.StatusCode, resp.Header.Get("Location"))
@@ -552,12 +613,7 @@ func (l LogRedirects) RoundTrip(req *http.Request) (resp *http.Response, err err
 var useChunkedInRequest = env.Bool("CHUNKED", false)
 
 func (b *Request) SendOut() (*http.Response, error) {
-	full := b.url
-	for _, q := range b.urlQuery {
-		full = appendURL(full, q)
-	}
-
-	u, err := url.Parse(full)
+	u, err := url.Parse(b.fullURL(b.url))
 	if err != nil {
 		return nil, err
 	}
@@ -566,12 +622,20 @@ func (b *Request) SendOut() (*http.Response, error) {
 
 	var jar http.CookieJar
 	if b.Setting.EnableCookie {
-		jar, _ = cookiejar.New(nil)
+		jar = cookieJar()
+		// the client adds the cookies of the jar into the request, which is reused in the -n iterations.
+		defer func(cookies []string) {
+			if b.Req.Header.Del("Cookie"); len(cookies) > 0 {
+				b.Req.Header["Cookie"] = cookies
+			}
+		}(b.Req.Header["Cookie"])
 	}
 
+	b.roundTrips = nil
 	client := &http.Client{
-		Transport: LogRedirects{RoundTripper: b.Transport},
-		Jar:       jar,
+		Transport:     &LogRedirects{RoundTripper: b.Transport, req: b},
+		Jar:           jar,
+		CheckRedirect: b.checkRedirect,
 	}
 
 	if b.Setting.UserAgent != "" && b.Req.Header.Get("User-Agent") == "" {
@@ -590,10 +654,18 @@ func (b *Request) SendOut() (*http.Response, error) {
 			b.Req.TransferEncoding = []string{"chunked"}
 			b.Req.ContentLength = -1
 		}
-		dump, err := httputil.DumpRequest(b.Req, b.Setting.DumpBody)
+		dumpReq := b.Req
+		if jar != nil { // dump the cookies which will be added by the client
+			dumpReq = b.Req.Clone(b.Req.Context())
+			for _, c := range jar.Cookies(b.Req.URL) {
+				dumpReq.AddCookie(c)
+			}
+		}
+		dump, err := httputil.DumpRequest(dumpReq, b.Setting.DumpBody)
 		if err != nil {
 			println(err.Error())
 		}
+		b.Req.Body = dumpReq.Body
 		b.reqDump = dump
 	}
 
@@ -613,6 +685,7 @@ func (b *Request) SendOut() (*http.Response, error) {
 		b.Req.ContentLength = -1
 	}
 
+	b.replayableBody()
 	return client.Do(b.Req)
 }
 
@@ -654,18 +727,19 @@ func (b *Request) Bytes() ([]byte, error) {
 		return nil, nil
 	}
 	defer iox.Close(resp.Body)
-	if resp.Header.Get("Content-Encoding") == "gzip" {
-		reader, err1 := gzip.NewReader(resp.Body)
-		if err1 != nil {
-			return nil, err1
-		}
-		b.rspBody, err = io.ReadAll(reader)
-	} else {
-		b.rspBody, err = io.ReadAll(resp.Body)
+	wire := &countReader{Reader: resp.Body}
+	reader, err := decodeContent(wire, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
 	}
+	defer iox.Close(reader)
+	b.rspBody, err = io.ReadAll(reader)
+	b.rspWireSize = wire.n
 	if err != nil {
 		return nil, err
 	}
+	b.rspBody = toUTF8(b.rspBody, resp.Header.Get("Content-Type"))
+	b.rspBody = decodeCodecBody(b.rspBody, resp.Header.Get("Content-Type"))
 	return b.rspBody, nil
 }
 
@@ -748,6 +822,15 @@ func TimeoutDialer(cTimeout time.Duration, tlsConfig *tls.Config, debug bool, r,
 			LocalAddr: getLocalAddr(),
 		}
 
+		config := tlsConfig
+		if resolved, ok := resolveOverride(addr); ok {
+			if config != nil && config.ServerName == "" {
+				config = config.Clone()
+				config.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+			addr = resolved
+		}
+
 		fn := dialer.DialContext
 		if unixSocket != "" {
 			ud := &unixDialer{UnixSocket: filepath.Clean(unixSocket)}
@@ -756,10 +839,10 @@ func TimeoutDialer(cTimeout time.Duration, tlsConfig *tls.Config, debug bool, r,
 
 		if enableTLCP {
 			fn = createTlcpDialer(dialer, caFile)
-		} else if tlsConfig != nil {
+		} else if config != nil {
 			tlsDialer := &tls.Dialer{
 				NetDialer: dialer,
-				Config:    tlsConfig,
+				Config:    config,
 			}
 			fn = tlsDialer.DialContext
 		}
@@ -820,19 +903,25 @@ func printConnectState(conn net.Conn) {
 type MyConn struct {
 	net.Conn
 	r, w  *int64
+	id    int64
 	debug bool
 }
 
+var connSeq int64
+
 func NewMyConn(conn net.Conn, debug bool, r, w *int64) *MyConn {
-	return &MyConn{Conn: conn, debug: debug, r: r, w: w}
+	c := &MyConn{Conn: conn, debug: debug, r: r, w: w, id: atomic.AddInt64(&connSeq, 1)}
+	wireTrace.Event(c.id, "connect %s->%s (%T)", conn.LocalAddr(), conn.RemoteAddr(), conn)
+	return c
 }
 
 func (c *MyConn) Read(b []byte) (n int, err error) {
 	if n, err = c.Conn.Read(b); n > 0 {
 		atomic.AddInt64(c.r, int64(n))
 		if c.debug {
-			fmt.Printf("%s", b)
+			fmt.Printf("%s", b[:n])
 		}
+		wireTrace.Record(c.id, false, b[:n])
 	}
 	return
 }
@@ -843,10 +932,16 @@ func (c *MyConn) Write(b []byte) (n int, err error) {
 	}
 	if n, err = c.Conn.Write(b); n > 0 {
 		atomic.AddInt64(c.w, int64(n))
+		wireTrace.Record(c.id, true, b[:n])
 	}
 	return
 }
 
+func (c *MyConn) Close() error {
+	wireTrace.Event(c.id, "close")
+	return c.Conn.Close()
+}
+
 // Resolve resolves host www.google.co by dnsServer like 8.8.8.8:5
 func Resolve(host, dnsServer string) ([]string, error) {
 	// https://stackoverflow.com/questions/59889882/specifying-dns-server-for-lookup-in-go
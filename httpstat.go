@@ -39,7 +39,7 @@ func createClientTrace(req *Request) *httptrace.ClientTrace {
 			}
 		},
 		ConnectDone: func(net, addr string, err error) {
-			if err != nil {
+			if err != nil && retryTimes <= 0 && executeFailedFatal { // the error is returned to retryResponse or executeFailed otherwise
 				log.Fatalf("unable to connect to host %v: %v", addr, err)
 			}
 			req.stat.t2 = time.Now()
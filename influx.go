@@ -1,65 +1,215 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
 
-	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/bingoohuang/gg/pkg/ss"
 )
 
-func createTableWriter() table.Writer {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	style := table.StyleDefault
-	style.Format.Header = text.FormatDefault
-	t.SetStyle(style)
-	return t
+type Series struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]any           `json:"values"`
+	Partial bool              `json:"partial"`
 }
 
-type Series struct {
-	Name    string   `json:"name"`
-	Columns []string `json:"columns"`
-	Values  [][]any  `json:"values"`
+type InfluxStatementResult struct {
+	Series   []Series `json:"series"`
+	Messages []struct {
+		Level string `json:"level"`
+		Text  string `json:"text"`
+	} `json:"messages"`
+	Error       string `json:"error"`
+	StatementID int    `json:"statement_id"`
+	Partial     bool   `json:"partial"`
 }
 
 type InfluxQueryResult struct {
-	Results []struct {
-		Series      []Series `json:"series"`
-		StatementID int      `json:"statement_id"`
-	} `json:"results"`
+	Results []InfluxStatementResult `json:"results"`
+	Error   string                  `json:"error"`
 }
 
-func influxTablePrint(ugly bool, influxDB bool, dat []byte) bool {
+// influxTablePrint prints InfluxDB 1.x JSON query results, including the chunked ones by chunked=true,
+// and InfluxDB 2.x Flux annotated CSV results as tables.
+func influxTablePrint(ugly bool, influxDB bool, dat []byte, contentType string) bool {
 	if ugly || !influxDB {
 		return false
 	}
 
-	var qr InfluxQueryResult
-	_ = json.Unmarshal(dat, &qr)
-	if len(qr.Results) > 0 && len(qr.Results[0].Series) > 0 {
-		for _, series := range qr.Results[0].Series {
+	if ss.ContainsFold(contentType, "csv") {
+		return fluxTablePrint(dat)
+	}
+
+	results, err := parseInfluxQueryResults(dat)
+	if err != nil || len(results) == 0 {
+		return false
+	}
+
+	for i, result := range results {
+		if len(results) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(Color(fmt.Sprintf("statement %d:", result.StatementID), Magenta))
+		}
+		if result.Error != "" {
+			fmt.Println(Color("error: "+result.Error, Red))
+		}
+		for _, m := range result.Messages {
+			fmt.Println(Color(m.Level+": "+m.Text, Yellow))
+		}
+		for _, series := range result.Series {
 			influxSeriesPrint(series)
 		}
-		return true
+		if result.Error == "" && len(result.Series) == 0 && len(result.Messages) == 0 {
+			fmt.Println(Color("(empty)", Gray))
+		}
+	}
+
+	return true
+}
+
+// parseInfluxQueryResults decodes the (maybe chunked) JSON responses,
+// and merges the partial chunks of the same statement and series.
+func parseInfluxQueryResults(dat []byte) ([]InfluxStatementResult, error) {
+	var results []InfluxStatementResult
+	index := map[int]int{}
+
+	d := json.NewDecoder(bytes.NewReader(dat))
+	for {
+		var qr InfluxQueryResult
+		if err := d.Decode(&qr); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if qr.Error != "" {
+			results = append(results, InfluxStatementResult{Error: qr.Error})
+			continue
+		}
+
+		for _, r := range qr.Results {
+			i, ok := index[r.StatementID]
+			if !ok {
+				index[r.StatementID] = len(results)
+				results = append(results, r)
+				continue
+			}
+
+			merged := &results[i]
+			merged.Partial = r.Partial
+			merged.Messages = append(merged.Messages, r.Messages...)
+			if r.Error != "" {
+				merged.Error = r.Error
+			}
+			merged.Series = mergeInfluxSeries(merged.Series, r.Series)
+		}
+	}
+
+	return results, nil
+}
+
+func mergeInfluxSeries(dst, src []Series) []Series {
+	for _, s := range src {
+		if n := len(dst); n > 0 && dst[n-1].Partial && sameInfluxSeries(dst[n-1], s) {
+			dst[n-1].Values = append(dst[n-1].Values, s.Values...)
+			dst[n-1].Partial = s.Partial
+			continue
+		}
+		dst = append(dst, s)
+	}
+	return dst
+}
+
+func sameInfluxSeries(a, b Series) bool {
+	return a.Name == b.Name && formatInfluxTags(a.Tags) == formatInfluxTags(b.Tags)
+}
+
+func formatInfluxTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	return false
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + tags[k]
+	}
+	return strings.Join(pairs, ",")
 }
 
 func influxSeriesPrint(series Series) {
-	fmt.Printf("%s:\n", series.Name)
-	header := make([]any, 1+len(series.Columns))
-	header[0] = "#"
-	for i, h := range series.Columns {
-		header[i+1] = h
+	if tags := formatInfluxTags(series.Tags); tags != "" {
+		fmt.Printf("%s: %s\n", series.Name, Color(tags, Cyan))
+	} else {
+		fmt.Printf("%s:\n", series.Name)
 	}
 	tw := createTableWriter()
-	tw.AppendHeader(header)
+	tw.AppendHeader(tableHeader(series.Columns))
 
 	for i, cells := range series.Values {
 		tw.AppendRow(append([]any{fmt.Sprintf("%d", i+1)}, cells...))
 	}
-	tw.Render()
+	renderTable(tw)
 }
+
+// fluxTablePrint prints the annotated CSV of InfluxDB 2.x Flux query responses.
+// Tables with different schemas are separated by empty lines, and annotation rows start with #.
+// https://docs.influxdata.com/influxdb/v2/reference/syntax/annotated-csv/
+func fluxTablePrint(dat []byte) bool {
+	dat = bytes.ReplaceAll(dat, []byte("\r\n"), []byte("\n"))
+	blocks := regexp.MustCompile(`\n\s*\n`).Split(string(bytes.TrimSpace(dat)), -1)
+
+	printed := false
+	for _, block := range blocks {
+		r := csv.NewReader(strings.NewReader(block))
+		r.FieldsPerRecord = -1
+		r.Comment = '#'
+		records, err := r.ReadAll()
+		if err != nil || len(records) == 0 {
+			continue
+		}
+
+		header := records[0]
+		// the first column is the annotation column, which is always empty in header and data rows.
+		skip := 0
+		if len(header) > 0 && header[0] == "" {
+			skip = 1
+		}
+
+		if printed {
+			fmt.Println()
+		}
+		tw := createTableWriter()
+		tw.AppendHeader(tableHeader(header[skip:]))
+		for i, record := range records[1:] {
+			cells := []any{fmt.Sprintf("%d", i+1)}
+			for _, c := range record[min(skip, len(record)):] {
+				cells = append(cells, c)
+			}
+			tw.AppendRow(cells)
+		}
+		renderTable(tw)
+		printed = true
+	}
+
+	return printed
+}
+
+// lineProtocolReg matches an InfluxDB line protocol item like `cpu,host=a usage=0.5 1700000000000000000`.
+// https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+var lineProtocolReg = regexp.MustCompile(`^[A-Za-z_][\w.\-]*(,(\\.|[^\s,=\\])+=(\\.|[^\s,\\])+)*\s+(\\.|[^\s,=\\])+=.+$`)
+
+func isLineProtocol(arg string) bool { return lineProtocolReg.MatchString(arg) }
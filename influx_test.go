@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestParseInfluxQueryResults(t *testing.T) {
+	chunked := `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","v"],"values":[[1,1]],"partial":true}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","v"],"values":[[2,2]]}]}]}
+{"results":[{"statement_id":1,"error":"measurement not found"}]}`
+
+	results, err := parseInfluxQueryResults([]byte(chunked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expect 2 statements, got %d", len(results))
+	}
+	if s := results[0].Series; len(s) != 1 || len(s[0].Values) != 2 || s[0].Partial {
+		t.Errorf("partial chunks are not merged: %+v", s)
+	}
+	if results[1].Error != "measurement not found" {
+		t.Errorf("statement error is lost: %+v", results[1])
+	}
+}
+
+func TestIsLineProtocol(t *testing.T) {
+	for arg, want := range map[string]bool{
+		"cpu,host=a usage=0.5":                  true,
+		"cpu usage=1i 1700000000000000000":      true,
+		`cpu,host=a\ b msg="hello world",v=1`:   true,
+		"name=John Smith":                       false,
+		"q==select * from cpu where host = 'a'": false,
+		"X-Token:a b":                           false,
+	} {
+		if got := isLineProtocol(arg); got != want {
+			t.Errorf("isLineProtocol(%q) = %t, want %t", arg, got, want)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/bingoohuang/gg/pkg/codec/b64"
 	"github.com/bingoohuang/gg/pkg/fla9"
+	"github.com/bingoohuang/gg/pkg/man"
 	"github.com/bingoohuang/gg/pkg/osx"
 	"github.com/bingoohuang/gg/pkg/osx/env"
 	"github.com/bingoohuang/gg/pkg/rest"
@@ -51,7 +52,22 @@ func main() {
 		log.Fatalf("failed to parse args, %v", err)
 	}
 
-	nonFlagArgs := filter(fla9.Args())
+	args := fla9.Args()
+	if fromCurl != "" {
+		args = append(applyFromCurl(), args...)
+	}
+	if len(args) > 0 && args[0] == "history" { // gurl history [keyword...]
+		listHistory(args[1:])
+		return
+	}
+	if len(args) > 1 && args[0] == "replay" { // gurl replay ID [overrides...]
+		args = replayHistory(args[1])
+	}
+	var httpFileArgs []string
+	if len(args) > 1 && args[0] == "run" { // gurl run api.http [name...]
+		httpFileArgs, args = args[1:], nil
+	}
+	nonFlagArgs := filter(args)
 
 	if ver {
 		fmt.Println(v.Version())
@@ -70,6 +86,10 @@ func main() {
 		printV += "b"
 	}
 
+	if tableFormat != "" {
+		printV += "T"
+	}
+
 	parsePrintOption(printV)
 	freeInnerJSON = HasPrintOption(freeInnerJSONTag)
 	ugly = HasPrintOption(printUgly)
@@ -83,20 +103,74 @@ func main() {
 		defaultSetting.DumpBody = false
 	}
 
+	if sessionName != "" {
+		currentSession = loadSession(sessionName)
+		defaultSetting.EnableCookie = true
+	}
+	if cookieFileName != "" || cookieJarFile != "" {
+		cookieFile = loadCookieFile(cookieFileName)
+		defaultSetting.EnableCookie = true
+		if cookieJarFile != "" {
+			if err := cookieFile.Save(cookieJarFile); err != nil {
+				log.Fatalf("save cookie jar %s failed: %v", cookieJarFile, err)
+			}
+		}
+	}
+
+	assertions = parseAssertions(asserts)
+	if err := validateRedirectFlags(); err != nil {
+		log.Fatal(err)
+	}
+
+	if openapiFile != "" {
+		loadOpenAPI(openapiFile)
+		if operationID != "" {
+			urls = applyOperation(urls)
+		}
+	}
+
+	if replMode {
+		runREPL(urls, nonFlagArgs)
+		return
+	}
+
 	if len(urls) == 0 {
 		urls = []string{DryRequestURL}
 	}
 
+	if traceFile != "" {
+		wireTrace = OpenWireTrace(traceFile)
+		defer wireTrace.Close()
+	}
+
 	stdin := parseStdin()
 
+	if diffMode {
+		runDiff(urls, nonFlagArgs, stdin)
+		return
+	}
+
 	start := time.Now()
-	for _, urlAddr := range urls {
-		run(len(urls), urlAddr, nonFlagArgs, stdin)
+	if len(httpFileArgs) > 0 && isWorkflowFile(httpFileArgs[0]) {
+		runWorkflow(httpFileArgs[0], httpFileArgs[1:])
+	} else if len(httpFileArgs) > 0 && isPostmanFile(httpFileArgs[0]) {
+		runPostman(httpFileArgs[0], httpFileArgs[1:])
+	} else if len(httpFileArgs) > 0 {
+		runHTTPFile(httpFileArgs[0], httpFileArgs[1:])
+	} else {
+		for _, urlAddr := range urls {
+			run(len(urls), urlAddr, nonFlagArgs, stdin)
+		}
 	}
 
 	if HasPrintOption(printVerbose) {
 		log.Printf("complete, total cost: %s", time.Since(start))
 	}
+
+	if assertFailed.Load() > 0 {
+		wireTrace.Close()
+		os.Exit(assertFailedExitCode)
+	}
 }
 
 func parseStdin() io.Reader {
@@ -118,6 +192,62 @@ func parseStdin() io.Reader {
 var uploadFilePb *ProgressBar
 
 func run(totalUrls int, urlAddr string, nonFlagArgs []string, reader io.Reader) {
+	req, addrGen := prepareRequest(urlAddr, nonFlagArgs, reader)
+	if exportFormat != "" {
+		exportRequest(req, addrGen)
+		return
+	}
+
+	thinkerFn := func() {}
+	if thinker, _ := thinktime.ParseThinkTime(think); thinker != nil {
+		thinkerFn = func() {
+			thinker.Think(true)
+		}
+	}
+
+	if benchC > 1 { // AB bench
+		req.DumpRequest(false)
+		RunBench(req, thinkerFn)
+		return
+	}
+
+	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
+
+	for i := 0; benchN == 0 || i < benchN; i++ {
+		if i > 0 {
+			req.Reset()
+
+			if confirmNum > 0 && (i+1)%confirmNum == 0 {
+				surveyConfirm()
+			}
+
+			if benchN == 0 || i < benchN-1 {
+				thinkerFn()
+			}
+		}
+
+		start := time.Now()
+		if HasPrintOption(printVerbose) && benchN == 0 {
+			log.Printf("N: %d", i+1)
+		}
+		err := doRequest(req, addrGen)
+		if HasPrintOption(printVerbose) && totalUrls > 1 {
+			log.Printf("current request cost: %s", time.Since(start))
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("error: %v", err)
+			}
+			break
+		}
+	}
+}
+
+// prepareRequest creates the request by the URL and the items, the setups set the request further before building,
+// like the headers and the fields whose keys are not valid in the items.
+func prepareRequest(urlAddr string, nonFlagArgs []string, reader io.Reader,
+	setups ...func(*Request),
+) (*Request, func() *url.URL) {
 	if reader != nil && isMethodDefaultGet() {
 		method = http.MethodPost
 	}
@@ -130,7 +260,7 @@ func run(totalUrls int, urlAddr string, nonFlagArgs []string, reader io.Reader)
 	).Data
 
 	addrGen := func() *url.URL { return u }
-	if urlAddr2 != urlAddr {
+	if urlAddr2 != urlAddr || refersCapturedVar(urlAddr) {
 		cnt := 0
 		addrGen = func() *url.URL {
 			cnt++
@@ -151,6 +281,9 @@ func run(totalUrls int, urlAddr string, nonFlagArgs []string, reader io.Reader)
 	}
 	realURL := u.String()
 	req := getHTTP(method, realURL, nonFlagArgs, timeout)
+	for _, setup := range setups {
+		setup(req)
+	}
 
 	if auth != "" {
 		// check if it is already set by base64 encoded
@@ -162,6 +295,9 @@ func run(totalUrls int, urlAddr string, nonFlagArgs []string, reader io.Reader)
 
 		req.Req.Header.Set("Authorization", "Basic "+auth)
 	}
+	if currentSession != nil {
+		currentSession.Apply(req)
+	}
 
 	req.Req = req.Req.WithContext(httptrace.WithClientTrace(req.Req.Context(), createClientTrace(req)))
 	setTimeoutRequest(req)
@@ -180,54 +316,10 @@ func run(totalUrls int, urlAddr string, nonFlagArgs []string, reader io.Reader)
 
 	req.BodyFileLines(body)
 
-	thinkerFn := func() {}
-	if thinker, _ := thinktime.ParseThinkTime(think); thinker != nil {
-		thinkerFn = func() {
-			thinker.Think(true)
-		}
-	}
-
 	req.SetupTransport()
 	req.BuildURL()
-
-	if benchC > 1 { // AB bench
-		req.DumpRequest(false)
-		RunBench(req, thinkerFn)
-		return
-	}
-
-	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
-
-	for i := 0; benchN == 0 || i < benchN; i++ {
-		if i > 0 {
-			req.Reset()
-
-			if confirmNum > 0 && (i+1)%confirmNum == 0 {
-				surveyConfirm()
-			}
-
-			if benchN == 0 || i < benchN-1 {
-				thinkerFn()
-			}
-		}
-
-		start := time.Now()
-		if HasPrintOption(printVerbose) && benchN == 0 {
-			log.Printf("N: %d", i+1)
-		}
-		err := doRequest(req, addrGen)
-		if HasPrintOption(printVerbose) && totalUrls > 1 {
-			log.Printf("current request cost: %s", time.Since(start))
-		}
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				log.Printf("error: %v", err)
-			}
-			break
-		}
-	}
+	return req, addrGen
 }
-
 func setTimeoutRequest(req *Request) {
 	if req.Timeout > 0 {
 		var cancelCtx context.Context
@@ -394,8 +486,17 @@ func doRequest(req *Request, addrGen func() *url.URL) error {
 
 	u := addrGen()
 	req.url = u.String()
+	req.EvalItems()
+	if openapiRouter != nil {
+		validateOpenAPIRequest(req)
+	}
 
+	recordHistory := historyRecorder(req)
 	doRequestInternal(req, u)
+	recordHistory()
+	if currentSession != nil {
+		currentSession.Update(req)
+	}
 	return nil
 }
 
@@ -421,11 +522,31 @@ func parseFileNameFromContentDisposition(header http.Header) (filename string) {
 	return ""
 }
 
+var (
+	// executeFailed reports the error of sending the request, which is not fatal in the REPL mode and the workflows.
+	executeFailed = func(err error) { log.Fatalf("execute error: %+v", err) }
+	// executeFailedFatal tells whether executeFailed exits, the connection errors are also returned to it if not.
+	executeFailedFatal = true
+)
+
 func doRequestInternal(req *Request, u *url.URL) {
 	if benchN == 0 || benchN > 1 {
 		req.Header("Gurl-N", fmt.Sprintf("%d", currentN.Inc()))
 	}
 
+	if outputFile != "" {
+		start := time.Now()
+		res, err := req.retryResponse()
+		if err != nil {
+			executeFailed(err)
+			return
+		}
+		writeOutput(req, res, u)
+		req.rspElapsed = time.Since(start)
+		checkResponse(req, res)
+		return
+	}
+
 	_, pathFile := path.Split(u.Path)
 	pathFileSize, pathFileExists, _ := Stat(pathFile)
 	if pathFileExists && pathFileSize > 0 {
@@ -460,21 +581,26 @@ func doRequestInternal(req *Request, u *url.URL) {
 		uploadFilePb.Start()
 	}
 
-	res, err := req.Response()
+	start := time.Now()
+	res, err := req.retryResponse()
 	if uploadFilePb != nil {
 		uploadFilePb.Finish()
 		fmt.Println()
 	}
 	if err != nil {
-		log.Fatalf("execute error: %+v", err)
+		executeFailed(err)
+		return
 	}
 
 	if processDownload(req, res, pathFileExists, dl, fn, pathFile) {
+		req.rspElapsed = time.Since(start)
+		checkResponse(req, res)
 		return
 	}
 
 	// 保证 response body 被 读取并且关闭
 	_, _ = req.Bytes()
+	req.rspElapsed = time.Since(start)
 
 	if isWindows() {
 		printRequestResponseForWindows(req, res)
@@ -483,8 +609,30 @@ func doRequestInternal(req *Request, u *url.URL) {
 	}
 
 	if HasPrintOption(printHTTPTrace) {
+		req.printRedirectTimings()
 		req.stat.print(u.Scheme)
 	}
+
+	checkResponse(req, res)
+}
+
+// checkResponse captures the values by -extract, validates the response by -openapi and checks it by -assert,
+// after the body is read, downloaded or written to -o.
+func checkResponse(req *Request, res *http.Response) {
+	if len(extracts) > 0 {
+		extractValues(res, req.rspBody)
+	}
+	if openapiRouter != nil {
+		validateOpenAPIResponse(req, res, req.rspBody)
+	}
+	if len(assertions) > 0 {
+		checkAssertions(assertions, res, req.rspBody, req.rspElapsed)
+	}
+}
+
+// keepResponseBody tells whether the body downloaded or written to -o should also be kept for checkResponse.
+func keepResponseBody() bool {
+	return len(assertions) > 0 || len(extracts) > 0 || openapiRouter != nil
 }
 
 func processDownload(req *Request, res *http.Response, pathFileExists bool, dl, fn, pathFile string) bool {
@@ -528,7 +676,7 @@ func processDownload(req *Request, res *http.Response, pathFileExists bool, dl,
 			}
 		}
 		if fn != "" {
-			downloadFile(req, res, fn)
+			downloadFile(req, res, fn, true)
 			return true
 		}
 	}
@@ -573,6 +721,9 @@ func printRequestResponseForNonWindows(req *Request, res *http.Response, downloa
 			fmt.Println(formatBytes(dumpBody, pretty, ugly, freeInnerJSON))
 		}
 	}
+	if HasAnyPrintOptions(printReqHeader, printRspHeader) {
+		req.printRedirects()
+	}
 
 	if !req.DryRequest {
 		influxDB := false
@@ -596,6 +747,10 @@ func printRequestResponseForNonWindows(req *Request, res *http.Response, downloa
 			if res.Close {
 				fmt.Printf("%s: %s\n", Color("Connection", Gray), Color("Close", Cyan))
 			}
+			if ce := res.Header.Get("Content-Encoding"); ce != "" && !download && req.rspBody != nil {
+				fmt.Println(Color(fmt.Sprintf("(%s decoded, %s on wire, %s decoded)", ce,
+					man.Bytes(uint64(req.rspWireSize)), man.Bytes(uint64(len(req.rspBody)))), Gray))
+			}
 
 			fmt.Println()
 		} else if HasPrintOption(printRspCode) {
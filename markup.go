@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+type markupKind int
+
+const (
+	markupText markupKind = iota
+	markupOpen
+	markupClose
+	markupSelfClose
+	markupComment
+	markupCData
+	markupDirective // <?xml ... ?>, <!DOCTYPE ...>
+)
+
+type markupToken struct {
+	text string
+	name string
+	kind markupKind
+}
+
+// htmlVoidElements are the HTML elements which have no end tags.
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawTextElements are the HTML elements whose content should be kept as it is.
+var htmlRawTextElements = map[string]bool{"script": true, "style": true, "pre": true, "textarea": true}
+
+// isMarkup tells whether the body looks like XML (SOAP, Atom, WebDAV multistatus and etc.) or HTML.
+func isMarkup(body []byte) bool {
+	body = bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	return len(body) > 1 && body[0] == '<' && body[len(body)-1] == '>'
+}
+
+func isHTML(body []byte) bool {
+	head := bytes.ToLower(body[:min(len(body), 512)])
+	return bytes.Contains(head, []byte("<!doctype html")) || bytes.Contains(head, []byte("<html"))
+}
+
+// tokenizeMarkup splits markup into tokens, the concatenation of whose texts is exactly the input.
+func tokenizeMarkup(s string, html bool) []markupToken {
+	var tokens []markupToken
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			j := strings.IndexByte(s[i:], '<')
+			if j < 0 {
+				j = len(s) - i
+			}
+			tokens = append(tokens, markupToken{kind: markupText, text: s[i : i+j]})
+			i += j
+			continue
+		}
+
+		end, kind := markupTagEnd(s[i:])
+		if end < 0 { // not a tag, take the < as text
+			tokens = append(tokens, markupToken{kind: markupText, text: s[i : i+1]})
+			i++
+			continue
+		}
+
+		t := markupToken{kind: kind, text: s[i : i+end]}
+		if kind == markupOpen || kind == markupClose || kind == markupSelfClose {
+			t.name = markupTagName(t.text)
+			if html && kind == markupOpen && htmlVoidElements[strings.ToLower(t.name)] {
+				t.kind = markupSelfClose
+			}
+		}
+		tokens = append(tokens, t)
+		i += end
+
+		if html && t.kind == markupOpen && htmlRawTextElements[strings.ToLower(t.name)] {
+			j := strings.Index(strings.ToLower(s[i:]), "</"+strings.ToLower(t.name))
+			if j < 0 {
+				j = len(s) - i
+			}
+			if j > 0 {
+				tokens = append(tokens, markupToken{kind: markupText, text: s[i : i+j]})
+			}
+			i += j
+		}
+	}
+
+	return tokens
+}
+
+// markupTagEnd returns the length of the tag at the beginning of s, and its kind.
+func markupTagEnd(s string) (int, markupKind) {
+	for _, c := range []struct {
+		prefix, suffix string
+		kind           markupKind
+	}{
+		{"<!--", "-->", markupComment},
+		{"<![CDATA[", "]]>", markupCData},
+		{"<?", "?>", markupDirective},
+	} {
+		if strings.HasPrefix(s, c.prefix) {
+			if j := strings.Index(s[len(c.prefix):], c.suffix); j >= 0 {
+				return len(c.prefix) + j + len(c.suffix), c.kind
+			}
+			return -1, c.kind
+		}
+	}
+
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			switch {
+			case strings.HasPrefix(s, "<!"):
+				return i + 1, markupDirective
+			case strings.HasPrefix(s, "</"):
+				return i + 1, markupClose
+			case s[i-1] == '/':
+				return i + 1, markupSelfClose
+			}
+			return i + 1, markupOpen
+		case c == '<':
+			return -1, markupText
+		}
+	}
+
+	return -1, markupText
+}
+
+func markupTagName(tag string) string {
+	tag = strings.TrimLeft(tag, "</")
+	if i := strings.IndexAny(tag, " \t\r\n/>"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// formatMarkup indents (pretty) or compacts (ugly) XML/HTML.
+func formatMarkup(body []byte, pretty, ugly bool) []byte {
+	if !pretty && !ugly {
+		return body
+	}
+
+	tokens := tokenizeMarkup(string(body), isHTML(body))
+	closeAt, inline := markupInline(tokens)
+	if ugly {
+		var out strings.Builder
+		for i, t := range tokens {
+			if t.kind == markupText && !inline[i] {
+				out.WriteString(strings.TrimSpace(t.text))
+			} else {
+				out.WriteString(markupInlineText(tokens, i))
+			}
+		}
+		return []byte(out.String())
+	}
+
+	var out strings.Builder
+	depth := 0
+	writeLine := func(s string) {
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(s)
+		out.WriteByte('\n')
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case markupText:
+			text := strings.TrimSpace(t.text)
+			if text == "" {
+				continue
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					writeLine(line)
+				}
+			}
+		case markupOpen:
+			// keep <a>text</a> and <a></a> in one line.
+			if i+1 < len(tokens) && tokens[i+1].kind == markupClose && tokens[i+1].name == t.name {
+				writeLine(t.text + tokens[i+1].text)
+				i++
+				continue
+			}
+			// keep the mixed content like <p>Hello <b>x</b> y</p> in one line, with its whitespace.
+			if j, ok := closeAt[i]; ok {
+				var line strings.Builder
+				for ; i <= j; i++ {
+					line.WriteString(markupInlineText(tokens, i))
+				}
+				i = j
+				writeLine(line.String())
+				continue
+			}
+			if i+2 < len(tokens) && tokens[i+1].kind == markupText && tokens[i+2].kind == markupClose &&
+				tokens[i+2].name == t.name && (!strings.Contains(strings.TrimSpace(tokens[i+1].text), "\n") ||
+				htmlRawTextElements[strings.ToLower(t.name)]) {
+				text := tokens[i+1].text
+				if !htmlRawTextElements[strings.ToLower(t.name)] {
+					text = strings.TrimSpace(text)
+				}
+				writeLine(t.text + text + tokens[i+2].text)
+				i += 2
+				continue
+			}
+			writeLine(t.text)
+			depth++
+		case markupClose:
+			depth = max(depth-1, 0)
+			writeLine(t.text)
+		default:
+			writeLine(t.text)
+		}
+	}
+
+	return []byte(strings.TrimSuffix(out.String(), "\n"))
+}
+
+var markupSpaceReg = regexp.MustCompile(`\s+`)
+
+// markupInline finds the elements with mixed content, that is the texts and the tags, like <p>Hello <b>x</b> y</p>,
+// whose whitespace is significant. It returns the index of the close tag for the open tag of such elements,
+// and whether each text token is inside one of them.
+func markupInline(tokens []markupToken) (closeAt map[int]int, inline []bool) {
+	type element struct {
+		open  int
+		mixed *bool
+	}
+	var stack []element
+	parents := make([]*bool, len(tokens))
+	closeAt = map[int]int{}
+	for i, t := range tokens {
+		switch t.kind {
+		case markupText:
+			if n := len(stack); n > 0 {
+				parents[i] = stack[n-1].mixed
+				if strings.TrimSpace(t.text) != "" {
+					*stack[n-1].mixed = true
+				}
+			}
+		case markupOpen:
+			stack = append(stack, element{open: i, mixed: new(bool)})
+		case markupClose:
+			// the HTML elements like <li> and <p> may have no close tags.
+			for k := len(stack) - 1; k >= 0; k-- {
+				if strings.EqualFold(tokens[stack[k].open].name, t.name) {
+					if *stack[k].mixed {
+						closeAt[stack[k].open] = i
+					}
+					stack = stack[:k]
+					break
+				}
+			}
+		}
+	}
+
+	inline = make([]bool, len(tokens))
+	for i, mixed := range parents {
+		inline[i] = mixed != nil && *mixed
+	}
+	return closeAt, inline
+}
+
+// markupInlineText returns the text of the token in the mixed content, the whitespace is collapsed
+// except in the raw text elements like <pre>.
+func markupInlineText(tokens []markupToken, i int) string {
+	t := tokens[i]
+	if t.kind != markupText || i > 0 && tokens[i-1].kind == markupOpen && htmlRawTextElements[strings.ToLower(tokens[i-1].name)] {
+		return t.text
+	}
+	return markupSpaceReg.ReplaceAllString(t.text, " ")
+}
+
+var markupAttrReg = regexp.MustCompile(`([^\s=/>]+)(\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+
+// ColorfulMarkup colors tags, attributes, comments and CDATA of XML/HTML, leaves texts as they are.
+func ColorfulMarkup(str string) string {
+	var out strings.Builder
+	for _, t := range tokenizeMarkup(str, isHTML([]byte(str))) {
+		switch t.kind {
+		case markupText:
+			out.WriteString(t.text)
+		case markupComment, markupDirective:
+			out.WriteString(Color(t.text, Gray))
+		case markupCData:
+			out.WriteString(Color("<![CDATA[", Gray))
+			out.WriteString(Color(t.text[len("<![CDATA["):len(t.text)-len("]]>")], Yellow))
+			out.WriteString(Color("]]>", Gray))
+		default:
+			out.WriteString(colorfulTag(t))
+		}
+	}
+	return out.String()
+}
+
+func colorfulTag(t markupToken) string {
+	start := strings.Index(t.text, t.name) + len(t.name)
+	end := len(t.text) - 1
+	if t.kind == markupSelfClose && strings.HasSuffix(t.text, "/>") {
+		end--
+	}
+	if start > end {
+		return Color(t.text, Magenta)
+	}
+
+	attrs := markupAttrReg.ReplaceAllStringFunc(t.text[start:end], func(attr string) string {
+		subs := markupAttrReg.FindStringSubmatch(attr)
+		return Color(subs[1], Cyan) + subs[2] + Color(subs[3], Green)
+	})
+	return Color(t.text[:start], Magenta) + attrs + Color(t.text[end:], Magenta)
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestFormatMarkup(t *testing.T) {
+	soap := `<?xml version="1.0"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">` +
+		`<soap:Body><m:Price xmlns:m="urn:x"><m:Item a='1'>Apple</m:Item><m:Note><![CDATA[<b>x</b>]]></m:Note><m:Empty/></m:Price>` +
+		`</soap:Body></soap:Envelope>`
+	want := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <m:Price xmlns:m="urn:x">
+      <m:Item a='1'>Apple</m:Item>
+      <m:Note>
+        <![CDATA[<b>x</b>]]>
+      </m:Note>
+      <m:Empty/>
+    </m:Price>
+  </soap:Body>
+</soap:Envelope>`
+	if got := string(formatMarkup([]byte(soap), true, false)); got != want {
+		t.Errorf("pretty got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := string(formatMarkup([]byte(want), false, true)); got != soap {
+		t.Errorf("ugly got:\n%s\nwant:\n%s", got, soap)
+	}
+
+	html := "<!DOCTYPE html><html><head><meta charset=utf-8><script>if (a < b) {}</script></head><body><br><p>hi</p></body></html>"
+	want = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset=utf-8>
+    <script>if (a < b) {}</script>
+  </head>
+  <body>
+    <br>
+    <p>hi</p>
+  </body>
+</html>`
+	if got := string(formatMarkup([]byte(html), true, false)); got != want {
+		t.Errorf("html got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatMarkupInline(t *testing.T) {
+	cases := []struct {
+		in, pretty, ugly string
+	}{
+		{`<p>Hello <b>x</b> y</p>`, `<p>Hello <b>x</b> y</p>`, `<p>Hello <b>x</b> y</p>`},
+		{"<div>\n  <p>Hello\n    <b>x</b>, <i>y</i> z</p>\n  <br/>\n</div>",
+			"<div>\n  <p>Hello <b>x</b>, <i>y</i> z</p>\n  <br/>\n</div>",
+			"<div><p>Hello <b>x</b>, <i>y</i> z</p><br/></div>"},
+	}
+	for _, c := range cases {
+		if got := string(formatMarkup([]byte(c.in), true, false)); got != c.pretty {
+			t.Errorf("pretty %q got:\n%s\nwant:\n%s", c.in, got, c.pretty)
+		}
+		if got := string(formatMarkup([]byte(c.in), false, true)); got != c.ugly {
+			t.Errorf("ugly %q got %q, want %q", c.in, got, c.ugly)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// openapiMaxBody is the max size of the request body to validate.
+const openapiMaxBody = 10 * 1024 * 1024
+
+var (
+	openapiDoc     *openapi3.T
+	openapiRouter  routers.Router
+	openapiServers []string
+
+	openapiHostReg  = regexp.MustCompile(`^[a-zA-Z][\w+.\-]*://[^/]*`)
+	openapiParamReg = regexp.MustCompile(`\{([^{}]+)}`)
+)
+
+// loadOpenAPI loads the -openapi spec, and creates the router to find the operation of the requests.
+func loadOpenAPI(filename string) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(filename)
+	if err != nil {
+		log.Fatalf("load openapi %s failed: %v", filename, err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		log.Printf("openapi %s is not valid: %v", filename, err)
+	}
+
+	// match the operations by the paths only, because the requests may go to the local or test servers.
+	servers := openapi3.Servers{}
+	for _, s := range doc.Servers {
+		openapiServers = append(openapiServers, s.URL)
+		servers = append(servers, &openapi3.Server{URL: "/" + strings.TrimLeft(openapiHostReg.ReplaceAllString(s.URL, ""), "/"),
+			Variables: s.Variables})
+	}
+	doc.Servers = servers
+
+	if openapiRouter, err = gorillamux.NewRouter(doc); err != nil {
+		log.Fatalf("create openapi router failed: %v", err)
+	}
+	openapiDoc = doc
+	openapi3.SchemaErrorDetailsDisabled = true
+}
+
+// applyOperation sets the method and the URL path by -op operationId. The path parameters like {id} are
+// evaluated as @{id}, from the env, -extract values or the interactive input.
+func applyOperation(urls []string) []string {
+	for path, item := range openapiDoc.Paths.Map() {
+		for m, op := range item.Operations() {
+			if op.OperationID != operationID {
+				continue
+			}
+
+			if !methodSpecifiedInArgs {
+				method = m
+			}
+			p := openapiParamReg.ReplaceAllString(path, "@{$1}")
+			if len(urls) == 0 {
+				if len(openapiServers) == 0 {
+					log.Fatalf("no URL specified and no servers in %s", openapiFile)
+				}
+				urls = []string{openapiServers[0]}
+			}
+			for i, u := range urls {
+				urls[i] = strings.TrimSuffix(u, "/") + p
+			}
+			return urls
+		}
+	}
+
+	log.Fatalf("operation %s not found in %s", operationID, openapiFile)
+	return nil
+}
+
+func openapiFullURL(req *Request) string {
+	return req.fullURL(req.url)
+}
+
+func findOpenAPIRoute(r *http.Request) (*openapi3filter.RequestValidationInput, error) {
+	route, pathParams, err := openapiRouter.FindRoute(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", r.Method, r.URL.Path, err)
+	}
+	return &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		},
+	}, nil
+}
+
+// validateOpenAPIRequest validates the request to be sent against the -openapi spec.
+func validateOpenAPIRequest(req *Request) {
+	u, err := url.Parse(openapiFullURL(req))
+	if err != nil {
+		return
+	}
+
+	r := req.Req.Clone(context.Background())
+	r.URL = u
+	input, err := findOpenAPIRoute(r)
+	if err != nil {
+		reportOpenAPIErrors("request", err)
+		return
+	}
+
+	if req.Req.Body != nil && req.Req.ContentLength > 0 && req.Req.ContentLength <= openapiMaxBody {
+		data, err := io.ReadAll(req.Req.Body)
+		if err != nil {
+			log.Fatalf("read request body failed: %v", err)
+		}
+		req.Req.Body = io.NopCloser(bytes.NewReader(data))
+		r.Body = io.NopCloser(bytes.NewReader(data))
+	} else {
+		r.Body = nil
+		input.Options.ExcludeRequestBody = true
+	}
+
+	reportOpenAPIErrors("request", openapi3filter.ValidateRequest(context.Background(), input))
+}
+
+// validateOpenAPIResponse validates the response status, headers and body against the -openapi spec.
+func validateOpenAPIResponse(req *Request, res *http.Response, body []byte) {
+	r := req.Req.Clone(context.Background())
+	r.Body = nil
+	input, err := findOpenAPIRoute(r)
+	if err != nil {
+		return // reported in validateOpenAPIRequest
+	}
+
+	reportOpenAPIErrors("response", openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 res.StatusCode,
+		Header:                 res.Header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                input.Options,
+	}))
+}
+
+// reportOpenAPIErrors prints the validation errors, and counts them as the failed assertions.
+func reportOpenAPIErrors(where string, err error) {
+	if err == nil {
+		return
+	}
+
+	for _, line := range openapiErrorLines("openapi "+where, err) {
+		fmt.Println(Color("✗ "+line, Red))
+		assertFailed.Inc()
+	}
+}
+
+// openapiErrorLines flattens the validation errors, with the JSON pointers of the schema errors, like
+// openapi response body /data/0/id: value must be an integer.
+func openapiErrorLines(where string, err error) (lines []string) {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, sub := range e {
+			lines = append(lines, openapiErrorLines(where, sub)...)
+		}
+		return lines
+	case *openapi3filter.RequestError:
+		switch {
+		case e.Parameter != nil:
+			where += fmt.Sprintf(" %s parameter %s", e.Parameter.In, e.Parameter.Name)
+		case e.RequestBody != nil:
+			where += " body"
+		}
+		if e.Err != nil {
+			return openapiErrorLines(where, e.Err)
+		}
+		return []string{where + ": " + e.Reason}
+	case *openapi3filter.ResponseError:
+		if e.Err != nil {
+			if strings.Contains(e.Reason, "body") {
+				where += " body"
+			} else if strings.Contains(e.Reason, "header") {
+				where += " header"
+			}
+			return openapiErrorLines(where, e.Err)
+		}
+		return []string{where + ": " + e.Reason}
+	case *openapi3.SchemaError:
+		reason := e.Reason
+		if reason == "" {
+			reason = "doesn't match schema " + e.SchemaField
+		}
+		return []string{where + " /" + strings.Join(e.JSONPointer(), "/") + ": " + reason}
+	}
+	return []string{where + ": " + err.Error()}
+}
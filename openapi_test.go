@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+func TestOpenAPIErrorLines(t *testing.T) {
+	schema := openapi3.NewObjectSchema().
+		WithProperty("id", openapi3.NewIntegerSchema()).
+		WithProperty("tags", openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()))
+	schemaErr := schema.VisitJSON(map[string]any{"id": "x", "tags": []any{"a", 1}}, openapi3.MultiErrors())
+	if schemaErr == nil {
+		t.Fatal("expected schema errors")
+	}
+
+	err := openapi3.MultiError{
+		&openapi3filter.RequestError{RequestBody: &openapi3.RequestBody{}, Err: schemaErr},
+		&openapi3filter.RequestError{Parameter: &openapi3.Parameter{In: "query", Name: "n"}, Reason: "required"},
+		errors.New("boom"),
+	}
+	disabled := openapi3.SchemaErrorDetailsDisabled
+	t.Cleanup(func() { openapi3.SchemaErrorDetailsDisabled = disabled })
+	openapi3.SchemaErrorDetailsDisabled = true
+	got := openapiErrorLines("openapi request", err)
+	want := []string{
+		"openapi request body /id: value must be an integer",
+		"openapi request body /tags/1: value must be a string",
+		"openapi request query parameter n: required",
+		"openapi request: boom",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("openapiErrorLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/iox"
+	"go.uber.org/atomic"
+)
+
+var (
+	outputSeq         atomic.Int64
+	outputTemplateReg = regexp.MustCompile(`\{(\w+)}`)
+)
+
+// writeOutput writes the response body to the file specified by -o, or to stdout by -o -.
+func writeOutput(req *Request, res *http.Response, u *url.URL) {
+	if outputFile == "-" {
+		r, err := decodeContent(res.Body, res.Header.Get("Content-Encoding"))
+		if err != nil {
+			iox.Close(res.Body)
+			log.Fatalf("decode body failed: %v", err)
+		}
+		defer iox.Close(r)
+		if _, err := io.Copy(os.Stdout, req.keepBody(r)); err != nil {
+			log.Fatalf("write body to stdout failed: %v", err)
+		}
+		return
+	}
+
+	fn := expandOutputTemplate(outputFile, res, u, outputSeq.Inc(), time.Now())
+	if dir := filepath.Dir(fn); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Fatalf("create dir %q failed: %v", dir, err)
+		}
+	}
+	downloadFile(req, res, fn, false)
+}
+
+// expandOutputTemplate expands the placeholders in the -o template, like out-{host}-{n}.json.
+// {n}: sequence of the response, starts from 1
+// {host}: host (and port) of the URL
+// {status}: response status code
+// {date}/{time}: current date as 20060102 and time as 150405
+// {name}: file name from Content-Disposition, or from the URL path
+// {ext}: extension by the Content-Type, like .json
+func expandOutputTemplate(tpl string, res *http.Response, u *url.URL, n int64, now time.Time) string {
+	return outputTemplateReg.ReplaceAllStringFunc(tpl, func(s string) string {
+		switch s[1 : len(s)-1] {
+		case "n":
+			return strconv.FormatInt(n, 10)
+		case "host":
+			return strings.ReplaceAll(u.Host, ":", "_")
+		case "status":
+			return strconv.Itoa(res.StatusCode)
+		case "date":
+			return now.Format("20060102")
+		case "time":
+			return now.Format("150405")
+		case "name":
+			if fn := filepath.Base(parseFileNameFromContentDisposition(res.Header)); fn != "." && fn != "/" {
+				return fn
+			}
+			if _, fn := path.Split(u.Path); fn != "" {
+				return fn
+			}
+			return "index"
+		case "ext":
+			if exts, _ := mime.ExtensionsByType(res.Header.Get("Content-Type")); len(exts) > 0 {
+				return exts[0]
+			}
+			return ""
+		}
+		return s
+	})
+}
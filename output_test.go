@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestExpandOutputTemplate(t *testing.T) {
+	u, _ := url.Parse("http://127.0.0.1:5003/api/demo")
+	res := &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}}}
+	now := time.Date(2026, 10, 19, 8, 30, 0, 0, time.Local)
+
+	cases := map[string]string{
+		"{host}-{n}{ext}":       "127.0.0.1_5003-3.json",
+		"{status}/{name}.{foo}": "200/demo.{foo}",
+		"{date}{time}.out":      "20261019083000.out",
+	}
+	for tpl, want := range cases {
+		if got := expandOutputTemplate(tpl, res, u, 3, now); got != want {
+			t.Errorf("%s: got %s, want %s", tpl, got, want)
+		}
+	}
+
+	res.Header.Set("Content-Disposition", `attachment; filename="../a.png"`)
+	if got := expandOutputTemplate("{name}", res, u, 1, now); got != "a.png" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// PostmanCollection is the Postman v2.1 collection, run by gurl run api.postman_collection.json.
+type PostmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []*PostmanItem `json:"item"`
+	Variable []PostmanKV    `json:"variable"`
+	Auth     *PostmanAuth   `json:"auth"`
+}
+
+// PostmanItem is a folder (with Item) or a request in the collection.
+type PostmanItem struct {
+	Name    string          `json:"name"`
+	Item    []*PostmanItem  `json:"item"`
+	Request *PostmanRequest `json:"request"`
+	Auth    *PostmanAuth    `json:"auth"`
+}
+
+type PostmanRequest struct {
+	Method string          `json:"method"`
+	Header []PostmanKV     `json:"header"`
+	URL    json.RawMessage `json:"url"` // string or {"raw": "..."}
+	Body   *PostmanBody    `json:"body"`
+	Auth   *PostmanAuth    `json:"auth"`
+}
+
+type PostmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []PostmanKV `json:"urlencoded"`
+	FormData   []PostmanKV `json:"formdata"`
+}
+
+type PostmanAuth struct {
+	Type   string      `json:"type"`
+	Basic  []PostmanKV `json:"basic"`
+	Bearer []PostmanKV `json:"bearer"`
+}
+
+// PostmanKV is the key/value used by variables, headers, bodies, auth and environment values.
+type PostmanKV struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Type     string `json:"type"`
+	Src      any    `json:"src"`
+	Disabled bool   `json:"disabled"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+func (kv PostmanKV) value() string {
+	if s, ok := kv.Value.(string); ok || kv.Value == nil {
+		return s
+	}
+	return workflowBody(kv.Value)
+}
+
+func (kv PostmanKV) active() bool {
+	return !kv.Disabled && (kv.Enabled == nil || *kv.Enabled)
+}
+
+func postmanKVs(kvs []PostmanKV) map[string]string {
+	m := map[string]string{}
+	for _, kv := range kvs {
+		if kv.active() {
+			m[kv.Key] = kv.value()
+		}
+	}
+	return m
+}
+
+func (r *PostmanRequest) rawURL() string {
+	var s string
+	if json.Unmarshal(r.URL, &s) == nil {
+		return s
+	}
+	var u struct {
+		Raw string `json:"raw"`
+	}
+	_ = json.Unmarshal(r.URL, &u)
+	return u.Raw
+}
+
+// isPostmanFile tells whether the file is a Postman collection by its info.schema, like
+// https://schema.getpostman.com/json/collection/v2.1.0/collection.json.
+func isPostmanFile(filename string) bool {
+	if !strings.EqualFold(filepath.Ext(filename), ".json") {
+		return false
+	}
+	var c struct {
+		Info struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+	}
+	return readJSONFile(filename, &c) == nil && strings.Contains(c.Info.Schema, "postman.com/json/collection/")
+}
+
+// postmanRequest is a request in the collection with its folder path and the inherited auth.
+type postmanRequest struct {
+	Path string
+	*PostmanItem
+	Auth *PostmanAuth
+}
+
+func flattenPostman(items []*PostmanItem, parent string, auth *PostmanAuth) (requests []postmanRequest) {
+	for _, item := range items {
+		p := ss.If(parent == "", item.Name, parent+"/"+item.Name)
+		a := auth
+		if item.Auth != nil {
+			a = item.Auth
+		}
+		if item.Request != nil {
+			if item.Request.Auth != nil {
+				a = item.Request.Auth
+			}
+			requests = append(requests, postmanRequest{Path: p, PostmanItem: item, Auth: a})
+		}
+		requests = append(requests, flattenPostman(item.Item, p, a)...)
+	}
+	return requests
+}
+
+// listPostman prints the folder tree of the collection.
+func listPostman(items []*PostmanItem, indent string) {
+	for _, item := range items {
+		if item.Request != nil {
+			fmt.Printf("%s%s %s\n", indent, Color(item.Request.Method, Green), item.Name)
+		} else {
+			fmt.Printf("%s%s/\n", indent, Color(item.Name, Magenta))
+		}
+		listPostman(item.Item, indent+"  ")
+	}
+}
+
+// runPostman runs the requests in the selected folders or requests (like Folder/Sub or Folder/Login) of the
+// Postman collection, or lists the folder tree by -list.
+func runPostman(filename string, names []string) {
+	var c PostmanCollection
+	if err := readJSONFile(filename, &c); err != nil {
+		log.Fatalf("read postman collection %s failed: %v", filename, err)
+	}
+
+	if postmanList {
+		fmt.Println(c.Info.Name)
+		listPostman(c.Item, "  ")
+		return
+	}
+
+	vars := postmanKVs(c.Variable)
+	if postmanEnv != "" {
+		var env struct {
+			Values []PostmanKV `json:"values"`
+		}
+		if err := readJSONFile(postmanEnv, &env); err != nil {
+			log.Fatalf("read postman environment %s failed: %v", postmanEnv, err)
+		}
+		for k, v := range postmanKVs(env.Values) {
+			vars[k] = v
+		}
+	}
+	for k, v := range vars {
+		valuer.Map[k] = v
+	}
+
+	h := &HTTPFileRunner{Vars: vars, Responses: map[string]*httpFileResponse{}}
+	defaultAuth, defaultForm, found := auth, form, false
+	defer func(m, b string) { method, body, auth, form = m, b, defaultAuth, defaultForm }(method, body)
+	for _, r := range flattenPostman(c.Item, "", c.Auth) {
+		if len(names) == 0 || postmanSelected(r, names) {
+			found = true
+			h.sendPostman(filepath.Dir(filename), r, defaultAuth, defaultForm)
+		}
+	}
+	if !found {
+		log.Fatalf("no requests found for %v, use -list to show the requests", names)
+	}
+}
+
+func postmanSelected(r postmanRequest, names []string) bool {
+	for _, name := range names {
+		name = strings.Trim(name, "/")
+		if r.Path == name || r.Name == name || strings.HasPrefix(r.Path, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HTTPFileRunner) sendPostman(dir string, r postmanRequest, defaultAuth string, defaultForm bool) {
+	// the headers and the fields are set to the request directly, instead of the items like k:v and k=v,
+	// because the keys like user[name] and the values like =x are not valid in the items.
+	headers, params, files := map[string]string{}, map[string]string{}, map[string]string{}
+	raw := ""
+	for k, v := range postmanKVs(r.Request.Header) {
+		headers[k] = h.interpolate(v, 0)
+	}
+
+	form, auth, body = defaultForm, defaultAuth, ""
+	if r.Auth != nil {
+		switch r.Auth.Type {
+		case "basic":
+			basic := postmanKVs(r.Auth.Basic)
+			auth = h.interpolate(basic["username"]+":"+basic["password"], 0)
+		case "bearer":
+			headers["Authorization"] = "Bearer " + h.interpolate(postmanKVs(r.Auth.Bearer)["token"], 0)
+		}
+	}
+
+	if b := r.Request.Body; b != nil {
+		switch b.Mode {
+		case "raw":
+			raw = h.interpolate(b.Raw, 0) // sent as it is, without evaluating the @ variables
+		case "urlencoded":
+			form = true
+			for k, v := range postmanKVs(b.URLEncoded) {
+				params[k] = h.interpolate(v, 0)
+			}
+		case "formdata":
+			form = true
+			for _, kv := range b.FormData {
+				if !kv.active() {
+					continue
+				}
+				if kv.Type == "file" {
+					src := workflowBody(kv.Src)
+					if !filepath.IsAbs(src) {
+						src = filepath.Join(dir, src)
+					}
+					files[kv.Key] = src
+				} else {
+					params[kv.Key] = h.interpolate(kv.value(), 0)
+				}
+			}
+		}
+	}
+
+	fmt.Println(Color("### "+r.Path, Magenta))
+	jsonmap = map[string]any{}
+	method = strings.ToUpper(ss.Or(r.Request.Method, "GET"))
+	req, addrGen := prepareRequest(h.interpolate(r.Request.rawURL(), 0), nil, nil, func(req *Request) {
+		for k, v := range headers {
+			if strings.EqualFold(k, "Host") {
+				req.SetHost(v)
+			} else {
+				req.Header(k, v)
+			}
+		}
+		for k, v := range params {
+			req.Param(k, v)
+		}
+		for k, v := range files {
+			req.PostFile(k, v)
+		}
+		if raw != "" {
+			req.BodyRaw(raw)
+		}
+	})
+	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
+	if err := doRequest(req, addrGen); err != nil {
+		log.Fatalf("request %s failed: %v", r.Path, err)
+	}
+	fmt.Println()
+}
+
+func readJSONFile(filename string, v any) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
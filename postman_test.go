@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenPostman(t *testing.T) {
+	var c PostmanCollection
+	err := json.Unmarshal([]byte(`{
+"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+"item": [
+  {"name": "Auth", "item": [
+    {"name": "Login", "request": {"method": "POST", "url": {"raw": "{{host}}/login"},
+      "auth": {"type": "basic", "basic": [{"key": "username", "value": "u"}]}}}
+  ]},
+  {"name": "Users", "item": [
+    {"name": "Sub", "item": [{"name": "Get", "request": {"url": "{{host}}/users/1"}}]}
+  ]}
+]}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := flattenPostman(c.Item, "", c.Auth)
+	if len(requests) != 2 {
+		t.Fatalf("len(requests) = %d, want 2", len(requests))
+	}
+	if r := requests[0]; r.Path != "Auth/Login" || r.Auth.Type != "basic" || r.Request.rawURL() != "{{host}}/login" {
+		t.Errorf("requests[0] = %s %s %s", r.Path, r.Auth.Type, r.Request.rawURL())
+	}
+	if r := requests[1]; r.Path != "Users/Sub/Get" || r.Auth.Type != "bearer" || r.Request.rawURL() != "{{host}}/users/1" {
+		t.Errorf("requests[1] = %s %s %s", r.Path, r.Auth.Type, r.Request.rawURL())
+	}
+
+	for names, want := range map[string]bool{"Users": true, "Users/Sub/": true, "Get": true, "Use": false, "Auth": false} {
+		if got := postmanSelected(requests[1], []string{names}); got != want {
+			t.Errorf("postmanSelected(%s) = %v, want %v", names, got, want)
+		}
+	}
+}
+
+func TestRunPostman(t *testing.T) {
+	var forms []url.Values
+	var headers []http.Header
+	var raw []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/raw" {
+			raw, _ = io.ReadAll(r.Body)
+			return
+		}
+		_ = r.ParseMultipartForm(1 << 20)
+		forms, headers = append(forms, r.PostForm), append(headers, r.Header)
+		if r.MultipartForm != nil {
+			for k, files := range r.MultipartForm.File {
+				forms[len(forms)-1].Set(k+".file", files[0].Filename)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644)
+	fn := filepath.Join(dir, "api.postman_collection.json")
+	_ = os.WriteFile(fn, []byte(`{
+"info": {"name": "api", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+"variable": [{"key": "host", "value": "`+srv.URL+`"}],
+"item": [
+  {"name": "Form", "request": {"method": "POST", "url": "{{host}}/form",
+    "header": [{"key": "X-Sign", "value": "=abc"}],
+    "body": {"mode": "urlencoded", "urlencoded": [{"key": "user[name]", "value": "bingoo"}, {"key": "q", "value": "=x"}]}}},
+  {"name": "Upload", "request": {"method": "POST", "url": "{{host}}/upload",
+    "body": {"mode": "formdata", "formdata": [{"key": "meta[id]", "value": "1"}, {"key": "f", "type": "file", "src": "a.txt"}]}}},
+  {"name": "Raw", "request": {"method": "POST", "url": "{{host}}/raw",
+    "body": {"mode": "raw", "raw": "{\"email\": \"user@example.com\", \"id\": \"@uuid\", \"host\": \"{{host}}\"}"}}}
+]}`), 0o644)
+	notPostman := filepath.Join(dir, "data.json")
+	_ = os.WriteFile(notPostman, []byte(`{"info": {"name": "x"}}`), 0o644)
+
+	if !isPostmanFile(fn) || isPostmanFile(notPostman) {
+		t.Fatal("isPostmanFile mismatched")
+	}
+
+	runPostman(fn, nil)
+	if len(forms) != 2 {
+		t.Fatalf("got %d requests, want 2", len(forms))
+	}
+	if want := (url.Values{"user[name]": {"bingoo"}, "q": {"=x"}}); !reflect.DeepEqual(forms[0], want) {
+		t.Errorf("form got %v, want %v", forms[0], want)
+	}
+	if got := headers[0].Get("X-Sign"); got != "=abc" {
+		t.Errorf("X-Sign got %q", got)
+	}
+	if want := (url.Values{"meta[id]": {"1"}, "f.file": {"a.txt"}}); !reflect.DeepEqual(forms[1], want) {
+		t.Errorf("multipart got %v, want %v", forms[1], want)
+	}
+	if want := `{"email": "user@example.com", "id": "@uuid", "host": "` + srv.URL + `"}`; string(raw) != want {
+		t.Errorf("raw body got %s, want %s", raw, want)
+	}
+}
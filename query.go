@@ -0,0 +1,882 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bingoohuang/jj"
+)
+
+// queryJSON extracts parts of a JSON body by expr.
+// An expr starting with '.', '[' or '{' is evaluated as a jq subset, like `.items[] | select(.age > 10) | .name`,
+// otherwise it is taken as a gjson-style path, like `items.#.name`.
+// Bodies of JSON lines (one JSON value per line) are queried line by line.
+func queryJSON(body []byte, expr string) ([][]byte, error) {
+	body = bytes.TrimSpace(body)
+	if jj.ValidBytes(body) {
+		return queryJSONValue(body, expr)
+	}
+
+	var results [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if !jj.ValidBytes(line) {
+			return nil, fmt.Errorf("body is not JSON")
+		}
+		r, err := queryJSONValue(line, expr)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r...)
+	}
+
+	return results, nil
+}
+
+func queryJSONValue(body []byte, expr string) ([][]byte, error) {
+	if !isJqExpr(expr) {
+		if r := jj.GetBytes(body, expr); r.Exists() {
+			return [][]byte{[]byte(r.Raw)}, nil
+		}
+		return nil, nil
+	}
+
+	q, err := parseJq(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := decodeOrdered(json.NewDecoder(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := q(v)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]byte, 0, len(values))
+	for _, value := range values {
+		d, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, d)
+	}
+	return results, nil
+}
+
+func isJqExpr(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	return expr != "" && strings.ContainsRune(".[{", rune(expr[0]))
+}
+
+// jqObject is a JSON object which keeps the original order of its keys.
+type jqObject struct {
+	m    map[string]any
+	keys []string
+}
+
+func newJqObject() *jqObject { return &jqObject{m: map[string]any{}} }
+
+func (o *jqObject) Set(k string, v any) {
+	if _, ok := o.m[k]; !ok {
+		o.keys = append(o.keys, k)
+	}
+	o.m[k] = v
+}
+
+func (o *jqObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kd, _ := json.Marshal(k)
+		buf.Write(kd)
+		buf.WriteByte(':')
+		vd, err := json.Marshal(o.m[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vd)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeOrdered(d *json.Decoder) (any, error) {
+	d.UseNumber()
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return decodeOrderedToken(d, t)
+}
+
+func decodeOrderedToken(d *json.Decoder, t json.Token) (any, error) {
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+
+	switch delim {
+	case '{':
+		o := newJqObject()
+		for d.More() {
+			kt, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			vt, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrderedToken(d, vt)
+			if err != nil {
+				return nil, err
+			}
+			o.Set(kt.(string), v)
+		}
+		_, err := d.Token() // }
+		return o, err
+	case '[':
+		a := []any{}
+		for d.More() {
+			vt, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrderedToken(d, vt)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := d.Token() // ]
+		return a, err
+	}
+
+	return nil, fmt.Errorf("unexpected delimiter %s", delim)
+}
+
+// jqFn evaluates a jq filter on an input value, which may produce zero or more outputs.
+type jqFn func(v any) ([]any, error)
+
+var errJqSyntax = errors.New("jq syntax error")
+
+type jqParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseJq(expr string) (jqFn, error) {
+	tokens, err := tokenizeJq(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &jqParser{tokens: tokens}
+	f, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q in %s", errJqSyntax, p.tokens[p.pos], expr)
+	}
+	return f, nil
+}
+
+func tokenizeJq(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				if expr[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("%w: unterminated string in %s", errJqSyntax, expr)
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+		case strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], ">="), strings.HasPrefix(expr[i:], "<="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case strings.ContainsRune(".|,()[]{}:<>?", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for ; j < len(expr) && strings.ContainsRune("0123456789.eE+-", rune(expr[j])); j++ {
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		case jqIdentStart(expr[i:]):
+			j := i
+			for j < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			r, _ := utf8.DecodeRuneInString(expr[i:])
+			return nil, fmt.Errorf("%w: unexpected %q in %s", errJqSyntax, r, expr)
+		}
+	}
+	return tokens, nil
+}
+
+// jqIdentStart tells whether s starts with an identifier, _ or a letter in any language, like .名字.
+func jqIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func (p *jqParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *jqParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *jqParser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("%w: expect %q, got %q", errJqSyntax, t, got)
+	}
+	return nil
+}
+
+func (p *jqParser) parsePipe() (jqFn, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "|" {
+		p.next()
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = jqPipe(left, right)
+	}
+	return left, nil
+}
+
+func jqPipe(left, right jqFn) jqFn {
+	return func(v any) ([]any, error) {
+		lv, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, l := range lv {
+			rv, err := right(l)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rv...)
+		}
+		return out, nil
+	}
+}
+
+func (p *jqParser) parseComma() (jqFn, error) {
+	fns := []jqFn{}
+	for {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, f)
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	if len(fns) == 1 {
+		return fns[0], nil
+	}
+
+	return func(v any) ([]any, error) {
+		var out []any
+		for _, f := range fns {
+			r, err := f(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+		return out, nil
+	}, nil
+}
+
+func (p *jqParser) parseOr() (jqFn, error) {
+	return p.parseLogic("or", p.parseAnd, func(a, b bool) bool { return a || b })
+}
+
+func (p *jqParser) parseAnd() (jqFn, error) {
+	return p.parseLogic("and", p.parseCompare, func(a, b bool) bool { return a && b })
+}
+
+func (p *jqParser) parseLogic(op string, sub func() (jqFn, error), combine func(a, b bool) bool) (jqFn, error) {
+	left, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == op {
+		p.next()
+		right, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		left = jqBinary(left, right, func(a, b any) (any, error) {
+			return combine(jqTruthy(a), jqTruthy(b)), nil
+		})
+	}
+	return left, nil
+}
+
+func (p *jqParser) parseCompare() (jqFn, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op := p.peek(); op {
+	case "==", "!=", ">", "<", ">=", "<=":
+		p.next()
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return jqBinary(left, right, func(a, b any) (any, error) {
+			c := jqCompare(a, b)
+			switch op {
+			case "==":
+				return c == 0, nil
+			case "!=":
+				return c != 0, nil
+			case ">":
+				return c > 0, nil
+			case "<":
+				return c < 0, nil
+			case ">=":
+				return c >= 0, nil
+			default:
+				return c <= 0, nil
+			}
+		}), nil
+	}
+
+	return left, nil
+}
+
+func jqBinary(left, right jqFn, op func(a, b any) (any, error)) jqFn {
+	return func(v any) ([]any, error) {
+		lv, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, r := range rv {
+			for _, l := range lv {
+				x, err := op(l, r)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, x)
+			}
+		}
+		return out, nil
+	}
+}
+
+func (p *jqParser) parsePostfix() (jqFn, error) {
+	f, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek() {
+		case ".":
+			p.next()
+			if p.peek() == "[" {
+				continue
+			}
+			name, err := p.parseFieldName()
+			if err != nil {
+				return nil, err
+			}
+			f = jqPipe(f, jqField(name))
+		case "[":
+			idx, err := p.parseIndex()
+			if err != nil {
+				return nil, err
+			}
+			f = jqPipe(f, idx)
+		case "?":
+			p.next()
+			f = jqTry(f)
+		default:
+			return f, nil
+		}
+	}
+}
+
+func (p *jqParser) parseFieldName() (string, error) {
+	t := p.next()
+	if strings.HasPrefix(t, `"`) {
+		return strconv.Unquote(t)
+	}
+	if !jqIdentStart(t) {
+		return "", fmt.Errorf("%w: bad field name %q", errJqSyntax, t)
+	}
+	return t, nil
+}
+
+// parseIndex parses [], [n], ["key"] and [from:to].
+func (p *jqParser) parseIndex() (jqFn, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.peek() == "]" {
+		p.next()
+		return jqIterate, nil
+	}
+
+	var from, to *int
+	if p.peek() != ":" {
+		t := p.next()
+		if strings.HasPrefix(t, `"`) {
+			name, err := strconv.Unquote(t)
+			if err != nil {
+				return nil, err
+			}
+			return jqField(name), p.expect("]")
+		}
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad index %q", errJqSyntax, t)
+		}
+		from = &n
+	}
+
+	if p.peek() != ":" {
+		return jqIndex(*from), p.expect("]")
+	}
+
+	p.next()
+	if p.peek() != "]" {
+		n, err := strconv.Atoi(p.next())
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad slice index", errJqSyntax)
+		}
+		to = &n
+	}
+	return jqSlice(from, to), p.expect("]")
+}
+
+func (p *jqParser) parsePrimary() (jqFn, error) {
+	switch t := p.next(); {
+	case t == ".":
+		switch next := p.peek(); {
+		case next == "[":
+			return jqIdentity, nil
+		case (strings.HasPrefix(next, `"`) || jqIdentStart(next)) && !jqKeyword(next):
+			name, err := p.parseFieldName()
+			if err != nil {
+				return nil, err
+			}
+			return jqField(name), nil
+		}
+		return jqIdentity, nil
+	case t == "(":
+		f, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case t == "[":
+		if p.peek() == "]" {
+			p.next()
+			return func(any) ([]any, error) { return []any{[]any{}}, nil }, nil
+		}
+		f, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(v any) ([]any, error) {
+			r, err := f(v)
+			if r == nil {
+				r = []any{}
+			}
+			return []any{r}, err
+		}, nil
+	case t == "{":
+		return p.parseObject()
+	case strings.HasPrefix(t, `"`):
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return nil, err
+		}
+		return jqConst(s), nil
+	case t != "" && (t[0] == '-' || t[0] >= '0' && t[0] <= '9'):
+		return jqConst(json.Number(t)), nil
+	case t == "true", t == "false":
+		return jqConst(t == "true"), nil
+	case t == "null":
+		return jqConst(nil), nil
+	case t == "select", t == "map":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if t == "select" {
+			return jqSelect(f), nil
+		}
+		return jqMap(f), nil
+	case jqBuiltins[t] != nil:
+		return jqBuiltins[t], nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", errJqSyntax, t)
+	}
+}
+
+// parseObject parses object construction like {name: .name, age}.
+func (p *jqParser) parseObject() (jqFn, error) {
+	type field struct {
+		name string
+		f    jqFn
+	}
+	var fields []field
+	for p.peek() != "}" {
+		name, err := p.parseFieldName()
+		if err != nil {
+			return nil, err
+		}
+		f := jqField(name)
+		if p.peek() == ":" {
+			p.next()
+			if f, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, field{name: name, f: f})
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	return func(v any) ([]any, error) {
+		o := newJqObject()
+		for _, fd := range fields {
+			r, err := fd.f(v)
+			if err != nil {
+				return nil, err
+			}
+			if len(r) > 0 {
+				o.Set(fd.name, r[0])
+			} else {
+				o.Set(fd.name, nil)
+			}
+		}
+		return []any{o}, nil
+	}, nil
+}
+
+func jqKeyword(t string) bool { return t == "and" || t == "or" }
+
+func jqIdentity(v any) ([]any, error) { return []any{v}, nil }
+
+func jqConst(c any) jqFn { return func(any) ([]any, error) { return []any{c}, nil } }
+
+func jqTry(f jqFn) jqFn {
+	return func(v any) ([]any, error) {
+		r, err := f(v)
+		if err != nil {
+			return nil, nil
+		}
+		return r, nil
+	}
+}
+
+func jqField(name string) jqFn {
+	return func(v any) ([]any, error) {
+		switch t := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case *jqObject:
+			return []any{t.m[name]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with %q", jqTypeName(v), name)
+	}
+}
+
+func jqIndex(i int) jqFn {
+	return func(v any) ([]any, error) {
+		switch t := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			j := i
+			if j < 0 {
+				j += len(t)
+			}
+			if j < 0 || j >= len(t) {
+				return []any{nil}, nil
+			}
+			return []any{t[j]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with number", jqTypeName(v))
+	}
+}
+
+func jqSlice(from, to *int) jqFn {
+	return func(v any) ([]any, error) {
+		a, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot slice %s", jqTypeName(v))
+		}
+		clamp := func(p *int, def int) int {
+			if p == nil {
+				return def
+			}
+			i := *p
+			if i < 0 {
+				i += len(a)
+			}
+			return max(0, min(i, len(a)))
+		}
+		f, t := clamp(from, 0), clamp(to, len(a))
+		if f > t {
+			f = t
+		}
+		return []any{a[f:t]}, nil
+	}
+}
+
+func jqIterate(v any) ([]any, error) {
+	switch t := v.(type) {
+	case []any:
+		return t, nil
+	case *jqObject:
+		out := make([]any, 0, len(t.keys))
+		for _, k := range t.keys {
+			out = append(out, t.m[k])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", jqTypeName(v))
+}
+
+func jqSelect(cond jqFn) jqFn {
+	return func(v any) ([]any, error) {
+		r, err := cond(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, x := range r {
+			if jqTruthy(x) {
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+}
+
+func jqMap(f jqFn) jqFn {
+	return func(v any) ([]any, error) {
+		items, err := jqIterate(v)
+		if err != nil {
+			return nil, err
+		}
+		out := []any{}
+		for _, item := range items {
+			r, err := f(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+		return []any{out}, nil
+	}
+}
+
+var jqBuiltins = map[string]jqFn{
+	"length": func(v any) ([]any, error) {
+		switch t := v.(type) {
+		case nil:
+			return []any{0}, nil
+		case string:
+			return []any{len([]rune(t))}, nil
+		case []any:
+			return []any{len(t)}, nil
+		case *jqObject:
+			return []any{len(t.keys)}, nil
+		case json.Number:
+			f, _ := t.Float64()
+			if f < 0 {
+				f = -f
+			}
+			return []any{f}, nil
+		}
+		return nil, fmt.Errorf("%s has no length", jqTypeName(v))
+	},
+	"keys": func(v any) ([]any, error) {
+		o, ok := v.(*jqObject)
+		if !ok {
+			return nil, fmt.Errorf("%s has no keys", jqTypeName(v))
+		}
+		keys := append([]string(nil), o.keys...)
+		sort.Strings(keys)
+		out := make([]any, len(keys))
+		for i, k := range keys {
+			out[i] = k
+		}
+		return []any{out}, nil
+	},
+	"first": jqIndex(0),
+	"last":  jqIndex(-1),
+	"not":   func(v any) ([]any, error) { return []any{!jqTruthy(v)}, nil },
+	"type":  func(v any) ([]any, error) { return []any{jqTypeName(v)}, nil },
+}
+
+func jqTruthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	}
+	return true
+}
+
+func jqTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, int, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case *jqObject:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jqCompare compares values in jq order: null < false < true < numbers < strings < arrays < objects.
+func jqCompare(a, b any) int {
+	ra, rb := jqRank(a), jqRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch ta := a.(type) {
+	case nil, bool:
+		return 0
+	case string:
+		return strings.Compare(ta, b.(string))
+	case json.Number, int, float64:
+		fa, fb := jqFloat(a), jqFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+	if reflect.DeepEqual(da, db) {
+		return 0
+	}
+	return bytes.Compare(da, db)
+}
+
+func jqRank(v any) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if t {
+			return 2
+		}
+		return 1
+	case json.Number, int, float64:
+		return 3
+	case string:
+		return 4
+	case []any:
+		return 5
+	}
+	return 6
+}
+
+func jqFloat(v any) float64 {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case int:
+		return float64(t)
+	case float64:
+		return t
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryJSON(t *testing.T) {
+	body := `{"code":0,"data":{"名字":"中文","items":[{"name":"a","age":5},{"name":"b","age":15},{"name":"c","age":25}]}}`
+	cases := []struct {
+		expr, want string
+	}{
+		{"code", `0`},
+		{"data.items.#.name", `["a","b","c"]`},
+		{".", body},
+		{".data.items[0].name", `"a"`},
+		{".data.items[-1].age", `25`},
+		{".data.items[] | .name", `"a" "b" "c"`},
+		{".data.items[] | select(.age > 10 and .name != \"c\") | .name", `"b"`},
+		{"[.data.items[] | .age]", `[5,15,25]`},
+		{".data.items | map(.name) | length", `3`},
+		{".data.items[1:] | map({n: .name})", `[{"n":"b"},{"n":"c"}]`},
+		{".data | keys", `["items","名字"]`},
+		{".data.items[0] | {age, name}", `{"age":5,"name":"a"}`},
+		{".missing.field", `null`},
+		{".data.名字", `"中文"`},
+		{`.data["名字"]`, `"中文"`},
+	}
+
+	for _, c := range cases {
+		results, err := queryJSON([]byte(body), c.expr)
+		if err != nil {
+			t.Fatalf("query %s: %v", c.expr, err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, string(r))
+		}
+		if s := strings.Join(got, " "); s != c.want {
+			t.Errorf("query %s: got %s, want %s", c.expr, s, c.want)
+		}
+	}
+}
+
+func TestQueryJSONLines(t *testing.T) {
+	results, err := queryJSON([]byte("{\"id\":1}\n{\"id\":2}\n"), ".id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || string(results[0]) != "1" || string(results[1]) != "2" {
+		t.Errorf("unexpected results: %q", results)
+	}
+
+	if _, err := queryJSON([]byte(`{"id":1}`), ".id |"); err == nil {
+		t.Error("expect syntax error")
+	}
+}
+
+func TestQueryJSONNegativeIndex(t *testing.T) {
+	body := `[[1,2,3],[4,5],[6,7,8,9]]`
+	cases := []struct {
+		expr, want string
+	}{
+		{"[.[] | .[-1]]", `[3,5,9]`},
+		{"[.[] | .[-2]]", `[2,4,8]`},
+		{"[.[] | .[-3]]", `[1,null,7]`},
+		{"[.[] | last]", `[3,5,9]`},
+		{". | map(last)", `[3,5,9]`},
+		{"[.[] | first]", `[1,4,6]`},
+	}
+
+	for _, c := range cases {
+		results, err := queryJSON([]byte(body), c.expr)
+		if err != nil {
+			t.Fatalf("query %s: %v", c.expr, err)
+		}
+		if len(results) != 1 || string(results[0]) != c.want {
+			t.Errorf("query %s: got %q, want %s", c.expr, results, c.want)
+		}
+	}
+}
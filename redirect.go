@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// redirectMaxBody is the max size of the request body to be buffered for replaying in the redirects.
+const redirectMaxBody = 1024 * 1024
+
+// roundTrip is a hop of the request, the ones before the last are the redirects.
+type roundTrip struct {
+	Method  string
+	URL     string
+	Status  int
+	Elapsed time.Duration
+	ReqDump []byte
+	RspDump []byte
+}
+
+// checkRedirect controls the redirects by -follow, -max-redirs, -keep-method and -redirect-auth.
+func (b *Request) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !followRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > maxRedirs {
+		return fmt.Errorf("stopped after %d redirects", maxRedirs)
+	}
+
+	prev, first := via[len(via)-1], via[0]
+	if req.Response != nil && req.Method != prev.Method &&
+		ss.AnyOf(strconv.Itoa(req.Response.StatusCode), splitFlagValues(keepMethods)...) {
+		req.Method = prev.Method
+		if prev.GetBody != nil {
+			body, err := prev.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body, req.GetBody, req.ContentLength = body, prev.GetBody, prev.ContentLength
+			if ct := first.Header.Get("Content-Type"); ct != "" {
+				req.Header.Set("Content-Type", ct)
+			}
+		}
+	}
+
+	switch authorization := first.Header.Get("Authorization"); redirectAuth {
+	case "all":
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+	case "origin":
+		if !sameOrigin(req.URL, first.URL) {
+			req.Header.Del("Authorization")
+		}
+	case "none":
+		req.Header.Del("Authorization")
+	default: // domain, Go's default, keep it only for the same domain or the subdomains
+	}
+	return nil
+}
+
+// redirectAuthPolicies are the available -redirect-auth values.
+var redirectAuthPolicies = []string{"domain", "origin", "all", "none"}
+
+// validateRedirectFlags rejects the bad -redirect-auth and -keep-method at startup, instead of at the first redirect.
+func validateRedirectFlags() error {
+	if !ss.AnyOf(redirectAuth, redirectAuthPolicies...) {
+		return fmt.Errorf("unknown -redirect-auth %s, available: %s", redirectAuth, strings.Join(redirectAuthPolicies, ", "))
+	}
+	for _, code := range splitFlagValues(keepMethods) {
+		if n, err := strconv.Atoi(code); err != nil || n < 300 || n > 399 {
+			return fmt.Errorf("bad -keep-method %s, expected the redirect status codes like 301,302", code)
+		}
+	}
+	return nil
+}
+
+// replayableBody buffers the small request body, so that it can be sent again in the 307/308 redirects,
+// or the 301/302/303 ones specified by -keep-method.
+func (b *Request) replayableBody() {
+	r := b.Req
+	r.GetBody = nil // the one of the previous -n iterations or retries
+	if !followRedirects || r.Body == nil || r.ContentLength <= 0 || r.ContentLength > redirectMaxBody {
+		return
+	}
+
+	data := make([]byte, r.ContentLength)
+	if _, err := io.ReadFull(r.Body, data); err != nil {
+		log.Fatalf("read request body failed: %v", err)
+	}
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	r.Body, _ = r.GetBody()
+}
+
+func (l *LogRedirects) record(req *http.Request, resp *http.Response, elapsed time.Duration) {
+	if l.req == nil {
+		return
+	}
+	if req.ProtoMajor == 0 { // the redirect requests created by http.Client have no proto
+		req = req.Clone(req.Context())
+		req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.1", 1, 1
+	}
+	reqDump, _ := httputil.DumpRequest(req, false)
+	rspDump, _ := httputil.DumpResponse(resp, false)
+	l.req.roundTrips = append(l.req.roundTrips, roundTrip{
+		Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode, Elapsed: elapsed,
+		ReqDump: reqDump, RspDump: rspDump,
+	})
+}
+
+// printRedirects prints the response headers of each redirect by -ph, and the request headers of the next hop by -pH.
+func (b *Request) printRedirects() {
+	for i := 0; i+1 < len(b.roundTrips); i++ {
+		hop, next := b.roundTrips[i], b.roundTrips[i+1]
+		fmt.Println(Color(fmt.Sprintf("--- redirect #%d: %d %s %s (%s)", i+1, hop.Status, hop.Method, hop.URL,
+			hop.Elapsed.Round(time.Millisecond)), Yellow))
+		if HasPrintOption(printRspHeader) {
+			fmt.Println(ColorfulRequest(strings.TrimSpace(string(hop.RspDump))))
+			fmt.Println()
+		}
+		if HasPrintOption(printReqHeader) {
+			fmt.Println(ColorfulRequest(strings.TrimSpace(string(next.ReqDump))))
+			fmt.Println()
+		}
+	}
+}
+
+// printRedirectTimings prints the timing of each hop by -pt, when there are redirects.
+func (b *Request) printRedirectTimings() {
+	if len(b.roundTrips) < 2 {
+		return
+	}
+	for i, hop := range b.roundTrips {
+		printf("hop #%d %s %s %s %s\n", i+1, Color(strconv.Itoa(hop.Status), Magenta), hop.Method, hop.URL,
+			Color(fmt.Sprintf("%d ms", hop.Elapsed.Milliseconds()), Cyan))
+	}
+}
+
+func sameOrigin(a, b *url.URL) bool { return a.Scheme == b.Scheme && a.Host == b.Host }
+
+// splitFlagValues splits the values of the repeatable flag by comma, like -keep-method 301,302 -keep-method 303.
+func splitFlagValues(values []string) (result []string) {
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRedirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/found":
+			http.Redirect(w, r, "/show", http.StatusFound)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			_, _ = io.WriteString(w, r.Method+" "+string(body)+" "+r.Header.Get("Authorization"))
+		}
+	}))
+}
+
+func sendRedirect(t *testing.T, addr, method, body string) (*Request, *http.Response, string) {
+	req := NewRequest(addr, method)
+	req.Header("Authorization", "Bearer x")
+	if body != "" {
+		req.BodyString(body)
+	}
+	res, err := req.SendOut()
+	if err != nil {
+		return req, nil, err.Error()
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return req, res, string(data)
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	srv := newRedirectServer()
+	defer srv.Close()
+	defer func() { followRedirects, maxRedirs, keepMethods, redirectAuth = true, 10, nil, "domain" }()
+	followRedirects, maxRedirs, redirectAuth = true, 10, "domain"
+
+	req, res, got := sendRedirect(t, srv.URL+"/found", "POST", "abc")
+	if got != "GET  Bearer x" || len(req.roundTrips) != 2 || req.roundTrips[0].Status != 302 {
+		t.Errorf("got %q, round trips %d", got, len(req.roundTrips))
+	}
+
+	keepMethods = []string{"301, 302", "303"}
+	if _, _, got = sendRedirect(t, srv.URL+"/found", "POST", "abc"); got != "POST abc Bearer x" {
+		t.Errorf("keep-method got %q", got)
+	}
+
+	redirectAuth = "none"
+	if _, _, got = sendRedirect(t, srv.URL+"/found", "GET", ""); got != "GET  " {
+		t.Errorf("redirect-auth none got %q", got)
+	}
+
+	maxRedirs = 2
+	if req, _, got = sendRedirect(t, srv.URL+"/loop", "GET", ""); len(req.roundTrips) != 3 || got == "" {
+		t.Errorf("max-redirs got %q, round trips %d", got, len(req.roundTrips))
+	}
+
+	followRedirects = false
+	if _, res, _ = sendRedirect(t, srv.URL+"/found", "GET", ""); res == nil || res.StatusCode != http.StatusFound {
+		t.Errorf("follow=false got %v", res)
+	}
+}
+
+func TestRedirectAuthOrigin(t *testing.T) {
+	show := newRedirectServer()
+	defer show.Close()
+	// 127.0.0.1 with a different port is the same domain for Go's default policy, but a different origin.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, show.URL+"/show", http.StatusFound)
+	}))
+	defer srv.Close()
+	defer func() { redirectAuth = "domain" }()
+
+	if _, _, got := sendRedirect(t, srv.URL, "GET", ""); got != "GET  Bearer x" {
+		t.Errorf("redirect-auth domain got %q", got)
+	}
+	redirectAuth = "origin"
+	if _, _, got := sendRedirect(t, srv.URL, "GET", ""); got != "GET  " {
+		t.Errorf("redirect-auth origin got %q", got)
+	}
+}
+
+func TestValidateRedirectFlags(t *testing.T) {
+	defer func() { keepMethods, redirectAuth = nil, "domain" }()
+	for _, c := range []struct {
+		auth  string
+		keep  []string
+		valid bool
+	}{
+		{"domain", []string{"301,302", "303"}, true},
+		{"origin", nil, true},
+		{"same", nil, false},
+		{"all", []string{"301,abc"}, false},
+		{"none", []string{"200"}, false},
+	} {
+		redirectAuth, keepMethods = c.auth, c.keep
+		if err := validateRedirectFlags(); (err == nil) != c.valid {
+			t.Errorf("%s %v: got %v", c.auth, c.keep, err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/iox"
+	"github.com/bingoohuang/gg/pkg/osx/env"
+	"github.com/bingoohuang/gg/pkg/rest"
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/chzyer/readline"
+	"github.com/kballard/go-shellquote"
+)
+
+const replHelp = `Commands:
+  METHOD [PATH|URL] [ITEM...]   Send the request to the base URL + PATH, with the items like id==3 name=x X-Token:abc
+  header [NAME:VALUE...]        Show the headers sent with all the requests, or set them, NAME: to remove
+  base [URL]                    Show or change the base URL
+  help                          Show this help
+  exit                          Exit, or Ctrl-D`
+
+// REPL is the interactive shell of gurl -i base-url, the base URL, headers, cookies and connections
+// persist across the commands.
+type REPL struct {
+	base    string
+	headers map[string]string
+	paths   map[string]bool
+
+	// readSum and writeSum are the bytes read and written on the shared connections.
+	readSum, writeSum int64
+}
+
+// runREPL runs the interactive shell, the header items in the command line are sent with all the requests.
+func runREPL(urls, items []string) {
+	r := &REPL{headers: map[string]string{}, paths: map[string]bool{}}
+	if len(urls) > 0 {
+		r.setBase(urls[0])
+	}
+	for _, item := range items {
+		if argKind(item) == ":" {
+			r.setHeader(item)
+		}
+	}
+
+	r.setup()
+	rl, err := NewLineReader(WithPrompt(r.prompt()), WithHistoryFile(replHistoryFile()), WithAutoComplete(r))
+	if err != nil {
+		log.Fatalf("create line reader failed: %v", err)
+	}
+	defer iox.Close(rl)
+
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if err != nil { // io.EOF by Ctrl-D
+			return
+		}
+		if h := redactLine(line); h != "" {
+			_ = rl.SaveHistory(h)
+		}
+		if !r.exec(line) {
+			return
+		}
+		rl.SetPrompt(r.prompt())
+	}
+}
+
+// setup shares the transport and the cookie jar, so that the keep-alive connections and cookies are reused,
+// and the failed requests are printed instead of exiting.
+func (r *REPL) setup() {
+	defaultSetting.Transport = r.newTransport()
+	defaultSetting.EnableCookie = true
+	executeFailed = func(err error) { fmt.Println(Color("✗ "+err.Error(), Red)) }
+	executeFailedFatal = false
+}
+
+// newTransport creates the transport shared by all the commands, set up once like SetupTransport does,
+// with the TLS config ($TLS_VERIFY, $CERT) for the https URLs and the proxy by the request.
+func (r *REPL) newTransport() *http.Transport {
+	tlsConfig := createTLSConfig(true)
+	debug := env.Bool("DEBUG", false)
+	return &http.Transport{
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		DisableKeepAlives:   disableKeepAlive,
+		TLSClientConfig:     tlsConfig,
+		Proxy:               func(req *http.Request) (*url.URL, error) { return parseProxyURL(req), nil },
+		DialContext:         TimeoutDialer(timeout, nil, debug, &r.readSum, &r.writeSum),
+		DialTLSContext:      TimeoutDialer(timeout, tlsConfig, debug, &r.readSum, &r.writeSum),
+	}
+}
+
+// redactLine redacts the secrets in the command line to be saved in the history, like header Authorization:xxx,
+// the invalid one is not saved.
+func redactLine(line string) string {
+	args, err := shellquote.Split(line)
+	if err != nil {
+		return ""
+	}
+	return quoteArgs(redactArgs(args))
+}
+
+func replHistoryFile() string {
+	if f := historyFile(); f != "" {
+		return filepath.Join(filepath.Dir(f), "repl_history")
+	}
+	return filepath.Join(os.TempDir(), "gurl-repl-history")
+}
+
+func (r *REPL) prompt() string {
+	return "gurl " + ss.Or(r.base, "-") + "> "
+}
+
+func (r *REPL) setBase(base string) {
+	r.base = strings.TrimSuffix(rest.FixURI(base,
+		rest.WithFatalErr(true),
+		rest.WithDefaultScheme(ss.If(caFile != "", "https", "http")),
+	).Data.String(), "/")
+}
+
+func (r *REPL) setHeader(item string) {
+	k, v, _ := strings.Cut(item, ":")
+	if v = strings.TrimSpace(v); v == "" {
+		delete(r.headers, http.CanonicalHeaderKey(k))
+	} else {
+		r.headers[http.CanonicalHeaderKey(k)] = v
+	}
+}
+
+// exec executes the command line, returns false to exit.
+func (r *REPL) exec(line string) bool {
+	args, err := shellquote.Split(line)
+	if err != nil {
+		fmt.Println(Color("✗ "+err.Error(), Red))
+		return true
+	}
+	if len(args) == 0 {
+		return true
+	}
+
+	switch cmd := args[0]; {
+	case ss.AnyOf(cmd, "exit", "quit"):
+		return false
+	case ss.AnyOf(cmd, "help", "?"):
+		fmt.Println(replHelp)
+	case cmd == "base":
+		if len(args) > 1 {
+			r.setBase(args[1])
+		}
+		fmt.Println(ss.Or(r.base, "no base URL, gurl -i base-url or base URL"))
+	case cmd == "header":
+		for _, item := range args[1:] {
+			r.setHeader(item)
+		}
+		for _, k := range sortedKeys(r.headers) {
+			fmt.Printf("%s: %s\n", Color(k, Cyan), r.headers[k])
+		}
+	case inSlice(strings.ToUpper(cmd), methodList):
+		r.send(strings.ToUpper(cmd), args[1:])
+	default:
+		fmt.Println(Color("✗ unknown command "+cmd+", type help for the commands", Red))
+	}
+	return true
+}
+
+// send sends the request of the method, with the optional path (or URL) and the items.
+func (r *REPL) send(m string, args []string) {
+	path := ""
+	if len(args) > 0 && argKind(args[0]) == "url" {
+		path, args = args[0], args[1:]
+	}
+	addr, ok := r.resolve(path)
+	if !ok {
+		fmt.Println(Color("✗ no base URL, set it by base URL, or use the full URL", Red))
+		return
+	}
+
+	var items []string
+	for _, k := range sortedKeys(r.headers) {
+		items = append(items, k+":"+r.headers[k])
+	}
+
+	method, methodSpecifiedInArgs, body = m, true, ""
+	jsonmap = map[string]any{}
+	req, addrGen := prepareRequest(addr, append(items, args...), nil)
+	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
+	_ = doRequest(req, addrGen)
+	if req.cancelTimeout != nil {
+		req.cancelTimeout()
+	}
+	if req.resp.StatusCode > 0 && path != "" && !ss.HasPrefix(path, "http://", "https://") {
+		p, _, _ := strings.Cut(path, "?")
+		r.paths["/"+strings.TrimPrefix(p, "/")] = true
+	}
+}
+
+func (r *REPL) resolve(path string) (string, bool) {
+	switch {
+	case ss.HasPrefix(path, "http://", "https://"):
+		return path, true
+	case r.base == "":
+		return "", false
+	case path == "":
+		return r.base, true
+	default:
+		return r.base + "/" + strings.TrimPrefix(path, "/"), true
+	}
+}
+
+// Do completes the commands, and the paths seen so far after the methods.
+func (r *REPL) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	s := string(line[:pos])
+	words := strings.Fields(s)
+	cur := ""
+	if len(words) > 0 && !strings.HasSuffix(s, " ") {
+		cur, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	var candidates []string
+	switch {
+	case len(words) == 0:
+		for _, m := range methodList {
+			candidates = append(candidates, m, strings.ToLower(m))
+		}
+		candidates = append(candidates, "header", "base", "help", "exit")
+	case len(words) == 1 && inSlice(strings.ToUpper(words[0]), methodList):
+		candidates = sortedKeys(r.paths)
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, cur) {
+			newLine = append(newLine, []rune(c[len(cur):]+" "))
+		}
+	}
+	return newLine, len([]rune(cur))
+}
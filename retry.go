@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// retryMaxBody is the max size of the request body to be buffered for replaying in the retries,
+// the larger ones are read again from the files.
+const retryMaxBody = 10 * 1024 * 1024
+
+// idempotentMethods are the methods safe to retry, others need -retry-force or the Idempotency-Key header.
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+// retryResponse sends the request, and retries it by -retry on the connection errors, timeouts
+// and the -retry-status codes, with the exponential backoff and jitter, or the Retry-After of the response.
+func (b *Request) retryResponse() (*http.Response, error) {
+	if retryTimes <= 0 {
+		return b.Response()
+	}
+
+	replay, replayable := b.bodyReplayer()
+	for attempt := 1; ; attempt++ {
+		res, err := b.Response()
+		reason := retryReason(res, err)
+		if reason == "" || attempt > retryTimes {
+			return res, err
+		}
+		if !replayable {
+			log.Printf("not retrying on %s, the request body can not be replayed", reason)
+			return res, err
+		}
+		if m := b.Req.Method; !retryForce && !ss.AnyOf(m, idempotentMethods...) && b.Req.Header.Get("Idempotency-Key") == "" {
+			log.Printf("not retrying %s on %s, use -retry-force to retry the non-idempotent methods", m, reason)
+			return res, err
+		}
+
+		wait := retryBackoff(attempt, res)
+		log.Printf("retry %d/%d in %s on %s", attempt, retryTimes, wait.Round(time.Millisecond), reason)
+		if res != nil && res.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, retryMaxBody))
+			res.Body.Close()
+		}
+		time.Sleep(wait)
+
+		b.resp = &http.Response{}
+		b.rspBody, b.rspWireSize = nil, 0
+		b.renewTimeout()
+		replay()
+	}
+}
+
+// retryReason returns why the response or the error should be retried, or empty for not.
+func retryReason(res *http.Response, err error) string {
+	if err != nil {
+		if retryableError(err) {
+			return err.Error()
+		}
+		return ""
+	}
+	if code := strconv.Itoa(res.StatusCode); ss.AnyOf(code, splitFlagValues([]string{retryStatus})...) {
+		return "status " + code
+	}
+	return ""
+}
+
+// retryableError tells whether the error is a connection error or a timeout.
+func retryableError(err error) bool {
+	var ne net.Error
+	// the -t timeout cancels the context of the request, see setTimeoutRequest.
+	if errors.As(err, &ne) && ne.Timeout() || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryBackoff returns the wait before the attempt, by the Retry-After of the response (seconds or HTTP date),
+// or -retry-wait * 2^(attempt-1) with the jitter in [half, full), both limited by -retry-max-wait.
+func retryBackoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if ra := res.Header.Get("Retry-After"); ra != "" {
+			if seconds, err := strconv.Atoi(ra); err == nil {
+				return min(time.Duration(max(seconds, 0))*time.Second, retryMaxWait)
+			}
+			if t, err := http.ParseTime(ra); err == nil {
+				return min(max(time.Until(t), 0), retryMaxWait)
+			}
+		}
+	}
+
+	wait := min(retryWait<<min(attempt-1, 30), retryMaxWait)
+	if half := wait / 2; half > 0 {
+		wait = half + time.Duration(rand.Int63n(int64(half)))
+	}
+	return wait
+}
+
+// bodyReplayer returns the func to set the request body again before the retries. The small body is buffered,
+// the larger ones from the files or uploads are read again, others can not be replayed.
+func (b *Request) bodyReplayer() (replay func(), replayable bool) {
+	r := b.Req
+	if r.Body == nil {
+		return func() {}, true
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, retryMaxBody+1))
+	if err != nil {
+		log.Fatalf("read request body failed: %v", err)
+	}
+	if len(data) <= retryMaxBody {
+		r.Body.Close()
+		contentLength := r.ContentLength
+		replay = func() { // b.Req may be renewed by renewTimeout
+			b.Req.Body, b.Req.ContentLength = io.NopCloser(bytes.NewReader(data)), contentLength
+		}
+		replay()
+		return replay, true
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{Reader: io.MultiReader(bytes.NewReader(data), r.Body), Closer: r.Body}
+	switch {
+	case len(uploadFiles) > 0:
+		return func() { setBody(b) }, true
+	case b.bodyData != nil:
+		return func() { b.RefreshBody() }, true
+	}
+	return nil, false
+}
+
+// renewTimeout creates a new context for the retry, because the -t timeout cancels the context of the request.
+func (b *Request) renewTimeout() {
+	if b.Timeout <= 0 {
+		return
+	}
+	if b.cancelTimeout != nil {
+		b.cancelTimeout()
+	}
+	b.Req = b.Req.WithContext(httptrace.WithClientTrace(context.Background(), createClientTrace(b)))
+	setTimeoutRequest(b)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryResponse(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1)%3 != 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, r.Method+" "+string(body))
+	}))
+	defer srv.Close()
+	defer func() { retryTimes, retryForce = 0, false }()
+
+	send := func(method string) (int, string) {
+		req := NewRequest(srv.URL, method)
+		req.BodyString("abc")
+		res, err := req.retryResponse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := req.Bytes()
+		return res.StatusCode, string(body)
+	}
+
+	retryTimes = 2
+	if code, body := send("PUT"); code != 200 || body != "PUT abc" || calls.Load() != 3 {
+		t.Errorf("PUT got %d %q after %d calls", code, body, calls.Load())
+	}
+
+	calls.Store(0)
+	if code, _ := send("POST"); code != 503 || calls.Load() != 1 {
+		t.Errorf("POST got %d after %d calls, want not retried", code, calls.Load())
+	}
+
+	calls.Store(0)
+	retryForce = true
+	if code, body := send("POST"); code != 200 || body != "POST abc" {
+		t.Errorf("forced POST got %d %q", code, body)
+	}
+
+	calls.Store(0)
+	retryTimes = 1
+	if code, _ := send("GET"); code != 503 || calls.Load() != 2 {
+		t.Errorf("GET got %d after %d calls, want 503 after 2 calls", code, calls.Load())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 30 * time.Second} {
+		if got := retryBackoff(attempt, nil); got < want/2 || got >= want {
+			t.Errorf("retryBackoff(%d) = %s, want in [%s, %s)", attempt, got, want/2, want)
+		}
+	}
+
+	res := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	if got := retryBackoff(1, res); got != 3*time.Second {
+		t.Errorf("Retry-After 3 got %s", got)
+	}
+	res.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if got := retryBackoff(1, res); got != retryMaxWait {
+		t.Errorf("Retry-After date got %s, want %s", got, retryMaxWait)
+	}
+
+	if !retryableError(syscall.ECONNREFUSED) || retryableError(errors.New("x509: certificate signed by unknown authority")) {
+		t.Error("retryableError mismatched")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/samber/lo"
+)
+
+// Session is the cookies, sticky headers and auth persisted by -session name,
+// in ~/.config/gurl/sessions/name.json, or the path of the name if it contains / or ends with .json.
+// The headers and auth are bound to the host of the first request, and are not sent to other hosts.
+type Session struct {
+	Host    string            `json:"host,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    string            `json:"auth,omitempty"`
+	Cookies []*SessionCookie  `json:"cookies,omitempty"`
+
+	file string
+	mu   sync.Mutex
+}
+
+// SessionCookie is the cookie set by the server of the URL.
+type SessionCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"httpOnly,omitempty"`
+}
+
+var (
+	currentSession *Session
+
+	sharedJar     http.CookieJar
+	sharedJarOnce sync.Once
+)
+
+// cookieJar returns the cookie jar shared by all the requests, so that the cookies survive between -n iterations,
+// bench workers and redirects.
+func cookieJar() http.CookieJar {
+	sharedJarOnce.Do(func() {
+		jar, _ := cookiejar.New(nil)
+		r := &recordingJar{CookieJar: jar}
+		if cookieFile != nil {
+			cookieFile.restoreCookies(jar)
+			r.recorders = append(r.recorders, cookieFile.SetCookies)
+		}
+		if currentSession != nil {
+			currentSession.restoreCookies(jar)
+			r.recorders = append(r.recorders, currentSession.SetCookies)
+		}
+		sharedJar = r
+	})
+	return sharedJar
+}
+
+func sessionFile(name string) string {
+	if strings.ContainsAny(name, `/\`) || strings.HasSuffix(name, ".json") {
+		return name
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatalf("get user config dir failed: %v", err)
+	}
+	return filepath.Join(dir, "gurl", "sessions", name+".json")
+}
+
+// loadSession loads the -session file, or creates a new one if it does not exist.
+func loadSession(name string) *Session {
+	s := &Session{file: sessionFile(name), Headers: map[string]string{}}
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("read session %s failed: %v", s.file, err)
+		}
+		return s
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		log.Fatalf("parse session %s failed: %v", s.file, err)
+	}
+	if s.Headers == nil {
+		s.Headers = map[string]string{}
+	}
+	return s
+}
+
+func (s *Session) restoreCookies(jar http.CookieJar) {
+	for _, c := range s.Cookies {
+		if u, err := url.Parse(c.URL); err == nil {
+			jar.SetCookies(u, []*http.Cookie{{
+				Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+				Expires: lo.FromPtr(c.Expires), Secure: c.Secure, HttpOnly: c.HttpOnly,
+			}})
+		}
+	}
+}
+
+// boundTo tells whether the session headers and auth are for the host of the request.
+func (s *Session) boundTo(req *Request) bool {
+	return s.Host == "" || strings.EqualFold(s.Host, req.Req.URL.Host)
+}
+
+// Apply sets the session headers and auth, which are not specified in the request, for the host of the session.
+func (s *Session) Apply(req *Request) {
+	if !s.boundTo(req) {
+		return
+	}
+	for k, v := range s.Headers {
+		if req.Req.Header.Get(k) == "" {
+			req.Header(k, v)
+		}
+	}
+	if s.Auth != "" && req.Req.Header.Get("Authorization") == "" {
+		req.Header("Authorization", s.Auth)
+	}
+}
+
+// sessionSkipHeaders are the headers not to be sticky in the session.
+var sessionSkipHeaders = []string{"Authorization", "Cookie", "Range"}
+
+// Update updates the session by the headers specified in the items and the auth of the request, and saves it.
+// The session is bound to the host of the first request, the requests to other hosts do not update it.
+func (s *Session) Update(req *Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.boundTo(req) {
+		return
+	}
+	s.Host = strings.ToLower(req.Req.URL.Host)
+	for _, k := range req.itemHeaders {
+		if v := req.Req.Header.Get(k); v != "" && !ss.AnyOf(k, sessionSkipHeaders...) && !ss.HasPrefix(k, "Content-", "If-") {
+			s.Headers[k] = v
+		}
+	}
+	if a := req.Req.Header.Get("Authorization"); a != "" {
+		s.Auth = a
+	}
+	s.save()
+}
+
+// SetCookies updates the session by the cookies set by the server of the URL.
+func (s *Session) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	origin := u.Scheme + "://" + u.Host
+	for _, c := range cookies {
+		kept := s.Cookies[:0]
+		for _, old := range s.Cookies {
+			if !(old.URL == origin && old.Name == c.Name && old.Domain == c.Domain && old.Path == c.Path) {
+				kept = append(kept, old)
+			}
+		}
+		s.Cookies = kept
+
+		expired := c.MaxAge < 0 || !c.Expires.IsZero() && c.Expires.Before(time.Now())
+		if !expired {
+			var expires *time.Time // nil for the session cookie
+			if c.MaxAge > 0 {
+				expires = lo.ToPtr(time.Now().Add(time.Duration(c.MaxAge) * time.Second))
+			} else if !c.Expires.IsZero() {
+				expires = lo.ToPtr(c.Expires)
+			}
+			s.Cookies = append(s.Cookies, &SessionCookie{
+				URL: origin, Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+				Expires: expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+			})
+		}
+	}
+	s.save()
+}
+
+func (s *Session) save() {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Fatalf("marshal session failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0o700); err != nil {
+		log.Fatalf("create session dir failed: %v", err)
+	}
+	if err := os.WriteFile(s.file, data, 0o600); err != nil {
+		log.Fatalf("write session %s failed: %v", s.file, err)
+	}
+}
+
+// recordingJar records the cookies set by the servers, including the ones in the redirects,
+// into the session and the cookie file.
+type recordingJar struct {
+	http.CookieJar
+	recorders []func(u *url.URL, cookies []*http.Cookie)
+}
+
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+	if len(cookies) > 0 {
+		for _, r := range j.recorders {
+			r(u, cookies)
+		}
+	}
+}
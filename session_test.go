@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "s.json")
+	s := loadSession(file)
+
+	u, _ := url.Parse("http://a.b/login")
+	s.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "1", Path: "/"}, {Name: "tmp", Value: "x", Path: "/"}})
+	s.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "2", Path: "/", MaxAge: 60}, {Name: "tmp", Path: "/", MaxAge: -1}})
+
+	req := NewRequest("http://a.b/users", http.MethodGet)
+	req.Header("X-Tenant", "t1")
+	req.Header("Content-Type", "application/json")
+	req.Header("Authorization", "Bearer tk")
+	req.Header("Beefs-Hash", "sha256:x")
+	req.itemHeaders = []string{"X-Tenant", "Content-Type", "Authorization"}
+	s.Update(req)
+
+	s = loadSession(file)
+	if len(s.Cookies) != 1 || s.Cookies[0].Value != "2" || s.Cookies[0].Expires == nil {
+		t.Fatalf("cookies = %+v", s.Cookies)
+	}
+	if len(s.Headers) != 1 || s.Headers["X-Tenant"] != "t1" || s.Auth != "Bearer tk" || s.Host != "a.b" {
+		t.Errorf("headers = %v, auth = %s, host = %s", s.Headers, s.Auth, s.Host)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	s.restoreCookies(jar)
+	if cookies := jar.Cookies(u); len(cookies) != 1 || cookies[0].Value != "2" {
+		t.Errorf("restored cookies = %v", cookies)
+	}
+
+	req = NewRequest("http://a.b/users", http.MethodGet)
+	req.Header("Authorization", "Basic xx")
+	s.Apply(req)
+	if req.Req.Header.Get("X-Tenant") != "t1" || req.Req.Header.Get("Authorization") != "Basic xx" {
+		t.Errorf("applied headers = %v", req.Req.Header)
+	}
+
+	// the headers and auth are not sent to, or updated by, other hosts.
+	req = NewRequest("http://c.d/users", http.MethodGet)
+	s.Apply(req)
+	if len(req.Req.Header) != 0 {
+		t.Errorf("applied headers to other host = %v", req.Req.Header)
+	}
+	req.Header("X-Tenant", "t2")
+	req.itemHeaders = []string{"X-Tenant"}
+	s.Update(req)
+	if s.Headers["X-Tenant"] != "t1" || s.Host != "a.b" {
+		t.Errorf("updated by other host, headers = %v, host = %s", s.Headers, s.Host)
+	}
+}
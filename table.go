@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+func createTableWriter() table.Writer {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	style := table.StyleDefault
+	style.Format.Header = text.FormatDefault
+	t.SetStyle(style)
+	return t
+}
+
+// renderTable renders the table in the format specified by -table, ascii (default), markdown or csv.
+func renderTable(tw table.Writer) {
+	switch strings.ToLower(tableFormat) {
+	case "md", "markdown":
+		tw.RenderMarkdown()
+	case "csv":
+		tw.RenderCSV()
+	default:
+		tw.Render()
+	}
+}
+
+// tablePrint prints a JSON array of objects, or a CSV body, as a table.
+func tablePrint(dat []byte, contentType string) bool {
+	if ss.ContainsFold(contentType, "text/csv") {
+		return csvTablePrint(dat)
+	}
+
+	return jsonTablePrint([][]byte{dat})
+}
+
+// jsonTablePrint prints JSON values as a table, whose columns are the union of the keys of all objects.
+// A single value should be an array of objects, multiple values should be objects.
+func jsonTablePrint(values [][]byte) bool {
+	var rows []*jqObject
+	for _, value := range values {
+		v, err := decodeOrdered(json.NewDecoder(bytes.NewReader(value)))
+		if err != nil {
+			return false
+		}
+
+		switch t := v.(type) {
+		case *jqObject:
+			rows = append(rows, t)
+		case []any:
+			if len(values) > 1 {
+				return false
+			}
+			for _, item := range t {
+				o, ok := item.(*jqObject)
+				if !ok {
+					return false
+				}
+				rows = append(rows, o)
+			}
+		default:
+			return false
+		}
+	}
+
+	if len(rows) == 0 {
+		return false
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, k := range row.keys {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+
+	tw := createTableWriter()
+	tw.AppendHeader(tableHeader(columns))
+	for i, row := range rows {
+		cells := table.Row{fmt.Sprintf("%d", i+1)}
+		for _, c := range columns {
+			cells = append(cells, tableCell(row.m[c]))
+		}
+		tw.AppendRow(cells)
+	}
+	renderTable(tw)
+	return true
+}
+
+func csvTablePrint(dat []byte) bool {
+	r := csv.NewReader(bytes.NewReader(dat))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		log.Printf("parse csv failed: %v", err)
+		return false
+	}
+	if len(records) == 0 {
+		return false
+	}
+
+	tw := createTableWriter()
+	tw.AppendHeader(tableHeader(records[0]))
+	for i, record := range records[1:] {
+		cells := table.Row{fmt.Sprintf("%d", i+1)}
+		for _, c := range record {
+			cells = append(cells, c)
+		}
+		tw.AppendRow(cells)
+	}
+	renderTable(tw)
+	return true
+}
+
+func tableHeader(columns []string) table.Row {
+	header := make(table.Row, 1+len(columns))
+	header[0] = "#"
+	for i, h := range columns {
+		header[i+1] = h
+	}
+	return header
+}
+
+func tableCell(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case *jqObject, []any:
+		d, _ := json.Marshal(t)
+		return string(d)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -62,7 +62,8 @@ func eatBlanks(s string) (blanks, left string) {
 }
 
 type Valuer struct {
-	Map map[string]any
+	// Map is the variables captured by -extract, Postman or workflows, Cache is the generated values like @uuid_1.
+	Map, Cache map[string]any
 	*jj.GenContext
 	InteractiveMode bool
 }
@@ -70,6 +71,7 @@ type Valuer struct {
 func NewValuer(interactiveMode bool) *Valuer {
 	return &Valuer{
 		Map:             make(map[string]any),
+		Cache:           make(map[string]any),
 		GenContext:      jj.NewGen(),
 		InteractiveMode: interactiveMode,
 	}
@@ -77,16 +79,21 @@ func NewValuer(interactiveMode bool) *Valuer {
 
 var cacheSuffix = regexp.MustCompile(`^(.+)_\d+`)
 
+// ClearCache clears the cached values like @uuid_1, keeps the captured variables.
 func (v *Valuer) ClearCache() {
-	v.Map = make(map[string]any)
+	v.Cache = make(map[string]any)
 }
 
 func (v *Valuer) Value(name, params, expr string) (any, error) {
+	if x, ok := v.Map[name]; ok {
+		return x, nil
+	}
+
 	pureName := name
 	subs := cacheSuffix.FindStringSubmatch(name)
 	if len(subs) > 0 {
 		pureName = subs[1]
-		if x, ok := v.Map[name]; ok {
+		if x, ok := v.Cache[name]; ok {
 			return x, nil
 		}
 	}
@@ -96,12 +103,20 @@ func (v *Valuer) Value(name, params, expr string) (any, error) {
 		return nil, err
 	}
 
-	if x == expr && v.InteractiveMode { // 没有解析成功，进入命令行输入模式
-		x = GetVar(name)
+	if x == expr {
+		if e, ok := lookupEnvVar(name); ok { // 从 .env 或者 @env.NAME 指定的环境变量中获取，例如 -extract-save 保存的值
+			return e, nil
+		}
+		if isExtractName(name) { // 尚未提取到，保持原样
+			return x, nil
+		}
+		if v.InteractiveMode { // 没有解析成功，进入命令行输入模式
+			x = GetVar(name)
+		}
 	}
 
 	if len(subs) > 0 {
-		v.Map[name] = x
+		v.Cache[name] = x
 	}
 
 	return x, nil
@@ -119,10 +134,11 @@ func GetVar(name string) string {
 }
 
 type LineConfig struct {
-	Prompt      string
-	HistoryFile string
-	Suffix      []string
-	TrimSuffix  bool
+	Prompt       string
+	HistoryFile  string
+	Suffix       []string
+	TrimSuffix   bool
+	AutoComplete readline.AutoCompleter
 }
 
 type LineConfigFn func(config *LineConfig)
@@ -145,7 +161,14 @@ func WithHistoryFile(historyFile string) LineConfigFn {
 	}
 }
 
-func ReadLine(fns ...LineConfigFn) (string, error) {
+func WithAutoComplete(completer readline.AutoCompleter) LineConfigFn {
+	return func(c *LineConfig) {
+		c.AutoComplete = completer
+	}
+}
+
+// NewLineReader creates the line reader with the prompt, history file and auto completion.
+func NewLineReader(fns ...LineConfigFn) (*readline.Instance, error) {
 	c := &LineConfig{
 		Prompt:      "> ",
 		HistoryFile: "/tmp/line",
@@ -154,11 +177,16 @@ func ReadLine(fns ...LineConfigFn) (string, error) {
 		fn(c)
 	}
 
-	rl, err := readline.NewEx(&readline.Config{
+	return readline.NewEx(&readline.Config{
 		Prompt:                 c.Prompt,
 		HistoryFile:            c.HistoryFile,
 		DisableAutoSaveHistory: true,
+		AutoComplete:           c.AutoComplete,
 	})
+}
+
+func ReadLine(fns ...LineConfigFn) (string, error) {
+	rl, err := NewLineReader(fns...)
 	if err != nil {
 		panic(err)
 	}
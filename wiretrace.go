@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// wireTrace records every plaintext byte read from or written to the connections, enabled by -trace-file.
+var wireTrace *WireTrace
+
+// WireTrace writes the traffic records of connections to a file.
+// Texts are written as they are, so the exact order and casing of headers are kept,
+// binary data are written as hex+ASCII dump.
+type WireTrace struct {
+	w  io.WriteCloser
+	mu sync.Mutex
+}
+
+func OpenWireTrace(filename string) *WireTrace {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Fatalf("open trace file %s failed: %v", filename, err)
+	}
+
+	return &WireTrace{w: f}
+}
+
+func (t *WireTrace) Close() error {
+	if t == nil {
+		return nil
+	}
+	return t.w.Close()
+}
+
+const wireTraceTimeLayout = "2006-01-02 15:04:05.000000"
+
+// Event records a connection event, like connect or close.
+func (t *WireTrace) Event(connID int64, format string, args ...any) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, _ = fmt.Fprintf(t.w, "== %s conn#%d %s\n", time.Now().Format(wireTraceTimeLayout), connID, fmt.Sprintf(format, args...))
+}
+
+// Record records the data sent (=>) or received (<=) on a connection.
+func (t *WireTrace) Record(connID int64, send bool, b []byte) {
+	if t == nil || len(b) == 0 {
+		return
+	}
+
+	dir, action := "<=", "recv"
+	if send {
+		dir, action = "=>", "send"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, _ = fmt.Fprintf(t.w, "%s %s conn#%d %s %d bytes\n", dir, time.Now().Format(wireTraceTimeLayout), connID, action, len(b))
+	if isPrintableText(b) {
+		_, _ = t.w.Write(b)
+		if b[len(b)-1] != '\n' {
+			_, _ = t.w.Write([]byte("\n"))
+		}
+	} else {
+		_, _ = io.WriteString(t.w, hex.Dump(b))
+	}
+}
+
+// isPrintableText tells whether b is UTF-8 text without control characters other than \t, \r and \n.
+func isPrintableText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\r' && c != '\n' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/bingoohuang/gg/pkg/thinktime"
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a YAML file of steps, run by gurl run flow.yaml, like:
+//
+//	name: user api
+//	vars:
+//	  host: http://127.0.0.1:8080
+//	steps:
+//	  - name: login
+//	    method: POST
+//	    url: '{{host}}/login'
+//	    items: [user=bingoo, pass=123]
+//	    extract: {token: data.token}
+//	    assert: [status==200]
+//	  - name: user
+//	    url: '{{host}}/users/{{item.id}}'
+//	    items: ['Authorization:Bearer {{token}}']
+//	    foreach: users.csv
+//	    skip_if: '{{item.id}}==0'
+type Workflow struct {
+	Name  string            `yaml:"name"`
+	Vars  map[string]string `yaml:"vars"`
+	Steps []*WorkflowStep   `yaml:"steps"`
+}
+
+// WorkflowStep is a step of the workflow, which may be repeated, or run for each item of the CSV/JSON data.
+type WorkflowStep struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Items   []string          `yaml:"items"`
+	Body    any               `yaml:"body"`
+	Extract map[string]string `yaml:"extract"`
+	Assert  []string          `yaml:"assert"`
+	Repeat  int               `yaml:"repeat"`
+	Foreach any               `yaml:"foreach"`
+	SkipIf  string            `yaml:"skip_if"`
+	Think   string            `yaml:"think"`
+
+	// assertions are the parsed Assert, whose {{var}} are interpolated before checking.
+	assertions []*Assertion
+}
+
+func isWorkflowFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// runWorkflow runs the steps, or the named ones, in the workflow file, and writes the JUnit report by -junit.
+func runWorkflow(filename string, names []string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Fatalf("read %s failed: %v", filename, err)
+	}
+
+	var w Workflow
+	if err := yaml.Unmarshal(data, &w); err != nil {
+		log.Fatalf("parse workflow %s failed: %v", filename, err)
+	}
+	if w.Vars == nil {
+		w.Vars = map[string]string{}
+	}
+	for i, step := range w.Steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		if step.assertions, err = parseStepAssertions(step.Assert); err != nil {
+			log.Fatalf("step %s of %s: %v", step.Name, filename, err)
+		}
+	}
+
+	h := &HTTPFileRunner{Vars: w.Vars, Responses: map[string]*httpFileResponse{}}
+	suite := &junitTestSuite{Name: ss.Or(w.Name, filepath.Base(filename))}
+	start := time.Now()
+	for _, step := range w.Steps {
+		if len(names) == 0 || ss.AnyOf(step.Name, names...) {
+			suite.Cases = append(suite.Cases, h.runStep(filepath.Dir(filename), step)...)
+		}
+	}
+	suite.Time = junitSeconds(time.Since(start))
+
+	if junitFile != "" {
+		if err := writeJUnitReport(junitFile, suite); err != nil {
+			log.Fatalf("write JUnit report failed: %v", err)
+		}
+	}
+}
+
+// parseStepAssertions parses the asserts when the workflow loads, so that the bad ones fail before sending,
+// the {{var}} are checked as 0, like time<{{max}}.
+func parseStepAssertions(exprs []string) ([]*Assertion, error) {
+	assertions := make([]*Assertion, 0, len(exprs))
+	for _, expr := range exprs {
+		a, err := parseAssertion(httpFileInterpReg.ReplaceAllString(expr, "0"))
+		if err != nil {
+			return nil, err
+		}
+		subs := assertReg.FindStringSubmatch(expr)
+		a.Expr, a.Subject, a.Expected = expr, subs[1], subs[3]
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+func (h *HTTPFileRunner) runStep(dir string, step *WorkflowStep) (cases []junitTestCase) {
+	items := []any{nil}
+	if step.Foreach != nil {
+		var err error
+		if items, err = loadForeachItems(dir, step.Foreach); err != nil {
+			log.Fatalf("load foreach of step %s failed: %v", step.Name, err)
+		}
+	}
+
+	index := 0
+	for _, item := range items {
+		for r := 0; r < max(step.Repeat, 1); r++ {
+			index++
+			h.setItemVars(item, index)
+			c := h.runStepOnce(step)
+			if len(items) > 1 || step.Repeat > 1 {
+				c.Name += fmt.Sprintf(" #%d", index)
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases
+}
+
+func (h *HTTPFileRunner) runStepOnce(step *WorkflowStep) junitTestCase {
+	c := junitTestCase{Name: h.interpolate(step.Name, 0), Classname: step.Name, Time: junitSeconds(0)}
+	if step.SkipIf != "" && evalCondition(h.interpolate(step.SkipIf, 0)) {
+		fmt.Println(Color("### "+c.Name+" (skipped)", Gray))
+		c.Skipped = &junitSkipped{Message: step.SkipIf}
+		return c
+	}
+
+	fmt.Println(Color("### "+c.Name, Magenta))
+	items := make([]string, len(step.Items))
+	for i, item := range step.Items {
+		items[i] = h.interpolate(item, 0)
+	}
+
+	defer func(m, b string, j map[string]any) { method, body, jsonmap = m, b, j }(method, body, jsonmap)
+	jsonmap = map[string]any{}
+	method, body = strings.ToUpper(ss.Or(step.Method, http.MethodGet)), h.interpolate(workflowBody(step.Body), 0)
+	req, addrGen := prepareRequest(h.interpolate(step.URL, 0), items, nil)
+	req.DumpRequest(HasAnyPrintOptions(printReqHeader, printReqBody))
+
+	// record the errors of sending as the failures, so that the following steps run and the JUnit report is written.
+	var failures []string
+	defer func(f func(error), fatal bool) { executeFailed, executeFailedFatal = f, fatal }(executeFailed, executeFailedFatal)
+	executeFailed, executeFailedFatal = func(err error) {
+		fmt.Println(Color("✗ "+err.Error(), Red))
+		failures = append(failures, "execute error: "+err.Error())
+		assertFailed.Inc()
+	}, false
+	if err := doRequest(req, addrGen); err != nil {
+		log.Fatalf("step %s failed: %v", step.Name, err)
+	}
+	c.Time = junitSeconds(req.rspElapsed)
+
+	if res := req.resp; res != nil && len(failures) == 0 {
+		for name, expr := range step.Extract {
+			v, err := (&Extraction{Name: name, Expr: h.interpolate(expr, 0)}).Extract(res, req.rspBody)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("extract %s failed: %v", name, err))
+				continue
+			}
+			valuer.Map[name] = v
+		}
+
+		assertions := make([]*Assertion, len(step.assertions))
+		for i, a := range step.assertions {
+			assertions[i] = &Assertion{Expr: h.interpolate(a.Expr, 0), Subject: h.interpolate(a.Subject, 0),
+				Op: a.Op, Expected: h.interpolate(a.Expected, 0)}
+		}
+		failures = append(failures, checkAssertions(assertions, res, req.rspBody, req.rspElapsed)...)
+		h.Responses[step.Name] = &httpFileResponse{Header: res.Header, Body: req.rspBody}
+	}
+	if len(failures) > 0 {
+		c.Failure = &junitFailure{Message: failures[0], Text: strings.Join(failures, "\n")}
+	}
+
+	if thinker, _ := thinktime.ParseThinkTime(ss.Or(step.Think, think)); thinker != nil {
+		thinker.Think(true)
+	}
+	fmt.Println()
+	return c
+}
+
+// setItemVars sets {{index}}, {{item}} and {{item.field}} for the current iteration.
+func (h *HTTPFileRunner) setItemVars(item any, index int) {
+	for k := range h.Vars {
+		if strings.HasPrefix(k, "item.") {
+			delete(h.Vars, k)
+		}
+	}
+	h.Vars["index"] = strconv.Itoa(index)
+	h.Vars["item"] = workflowBody(item)
+	if m, ok := item.(map[string]any); ok {
+		for k, v := range m {
+			h.Vars["item."+k] = workflowBody(v)
+		}
+	}
+}
+
+// loadForeachItems loads the items from an inline list, or a CSV (with header line) / JSON array file.
+func loadForeachItems(dir string, foreach any) ([]any, error) {
+	switch t := foreach.(type) {
+	case []any:
+		return t, nil
+	case string:
+		fn := t
+		if !filepath.IsAbs(fn) {
+			if _, err := os.Stat(fn); err != nil {
+				fn = filepath.Join(dir, fn)
+			}
+		}
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.EqualFold(filepath.Ext(fn), ".csv") {
+			rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+			if err != nil || len(rows) == 0 {
+				return nil, err
+			}
+			var items []any
+			for _, row := range rows[1:] {
+				m := map[string]any{}
+				for i, h := range rows[0] {
+					if i < len(row) {
+						m[h] = row[i]
+					}
+				}
+				items = append(items, m)
+			}
+			return items, nil
+		}
+
+		var items []any
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported foreach %v, should be a list or a .csv/.json file", foreach)
+}
+
+// workflowBody returns the string as it is, and the JSON for the others, like YAML maps.
+func workflowBody(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	}
+	d, err := json.Marshal(v)
+	if err != nil {
+		log.Fatalf("marshal %v failed: %v", v, err)
+	}
+	return string(d)
+}
+
+// evalCondition evaluates the condition like a==b, a!=b, a>1, a~regex, or a single value like true/false.
+func evalCondition(s string) bool {
+	if subs := assertReg.FindStringSubmatch(s); len(subs) > 0 {
+		a := &Assertion{Op: ss.If(subs[2] == "=", "==", subs[2]), Expected: subs[3]}
+		_, ok, _ := a.compare(subs[1])
+		return ok
+	}
+	s = strings.TrimSpace(s)
+	return s != "" && !ss.AnyOfFold(s, "false", "0", "no")
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name          `xml:"testsuites"`
+	Suites  []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func writeJUnitReport(filename string, suite *junitTestSuite) error {
+	suite.Tests = len(suite.Cases)
+	for _, c := range suite.Cases {
+		if c.Failure != nil {
+			suite.Failures++
+		}
+		if c.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []*junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
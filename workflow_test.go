@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadForeachItems(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "users.csv"), []byte("id,name\n1,a\n2,b\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "users.json"), []byte(`[{"id":1},{"id":2}]`), 0o644)
+
+	items, err := loadForeachItems(dir, "users.csv")
+	if want := []any{map[string]any{"id": "1", "name": "a"}, map[string]any{"id": "2", "name": "b"}}; err != nil ||
+		!reflect.DeepEqual(items, want) {
+		t.Errorf("got %v, %v", items, err)
+	}
+	if items, err = loadForeachItems(dir, "users.json"); err != nil || len(items) != 2 {
+		t.Errorf("got %v, %v", items, err)
+	}
+
+	h := &HTTPFileRunner{Vars: map[string]string{}}
+	h.setItemVars(items[1], 2)
+	if got := h.interpolate("/users/{{item.id}}?i={{index}}&j={{item}}", 0); got != `/users/2?i=2&j={"id":2}` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	cases := map[string]bool{"prod==prod": true, "a != a": false, "3>2": true, "abc~^a": true, "true": true, "0": false, "": false}
+	for s, want := range cases {
+		if got := evalCondition(s); got != want {
+			t.Errorf("%s: got %t", s, got)
+		}
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "report.xml")
+	suite := &junitTestSuite{Name: "api", Time: "0.100", Cases: []junitTestCase{
+		{Name: "login", Classname: "login", Time: "0.050"},
+		{Name: "user", Classname: "user", Time: "0.050", Failure: &junitFailure{Message: "m", Text: "m"}},
+		{Name: "skip", Classname: "skip", Skipped: &junitSkipped{Message: "x"}},
+	}}
+	if err := writeJUnitReport(fn, suite); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(fn)
+	if !strings.Contains(string(data), `<testsuite name="api" tests="3" failures="1" skipped="1" time="0.100">`) {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestRunWorkflowExecuteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	dir := t.TempDir()
+	fn, report := filepath.Join(dir, "flow.yaml"), filepath.Join(dir, "report.xml")
+	_ = os.WriteFile(fn, []byte(`steps:
+  - name: down
+    url: `+closed.URL+`/down
+  - name: up
+    url: `+srv.URL+`/up
+    assert: [status==200]
+`), 0o644)
+
+	defer func(f string, n int64) { junitFile = f; assertFailed.Store(n) }(junitFile, assertFailed.Load())
+	junitFile = report
+	runWorkflow(fn, nil)
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); !strings.Contains(s, `tests="2" failures="1"`) || !strings.Contains(s, "execute error") {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestRunWorkflowAsserts(t *testing.T) {
+	if _, err := parseStepAssertions([]string{"time<{{max}}", "body.ok=={{ok}}"}); err != nil {
+		t.Errorf("parseStepAssertions() = %v", err)
+	}
+	for _, expr := range []string{"status", "size<1XB", "bad.{{x}}==1"} {
+		if _, err := parseStepAssertions([]string{expr}); err == nil {
+			t.Errorf("parseStepAssertions(%s) should fail", expr)
+		}
+	}
+
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, strings.TrimSpace(r.Method+" "+string(data)))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	fn := filepath.Join(t.TempDir(), "flow.yaml")
+	_ = os.WriteFile(fn, []byte(`vars: {ok: "true", max: 10s}
+steps:
+  - name: create
+    method: POST
+    url: `+srv.URL+`/users
+    items: [name=a]
+    assert: ['body.ok=={{ok}}', 'time<{{max}}']
+  - name: get
+    url: `+srv.URL+`/users
+`), 0o644)
+
+	defer func(n int64) { assertFailed.Store(n) }(assertFailed.Load())
+	assertFailed.Store(0)
+	defer func(m, b string, j map[string]any) { method, body, jsonmap = m, b, j }(method, body, jsonmap)
+	method, body, jsonmap = "PUT", "x", map[string]any{"k": "v"}
+	runWorkflow(fn, nil)
+
+	if want := []string{`POST {"name":"a"}`, "GET"}; !reflect.DeepEqual(bodies, want) || assertFailed.Load() != 0 {
+		t.Errorf("bodies %q, want %q, assert failed %d", bodies, want, assertFailed.Load())
+	}
+	if method != "PUT" || body != "x" || !reflect.DeepEqual(jsonmap, map[string]any{"k": "v"}) {
+		t.Errorf("method %q, body %q and jsonmap %v are not restored", method, body, jsonmap)
+	}
+}